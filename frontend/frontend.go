@@ -17,19 +17,25 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"html/template"
+	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
 
 	"cloud.google.com/go/trace"
 	pb "github.com/GoogleCloudPlatform/gifinator/proto"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 )
 
 // TODO(jessup) remove globals in favor of appContext
@@ -51,6 +57,7 @@ func main() {
 	port = os.Getenv("FRONTEND_PORT")
 	gifcreatorPort := os.Getenv("GIFCREATOR_PORT")
 	gifcreatorName := os.Getenv("GIFCREATOR_NAME")
+	blobLocalDir := os.Getenv("BLOB_LOCAL_DIR")
 
 	// TODO(jessup): check env vars for correctnesss
 
@@ -79,7 +86,13 @@ func main() {
 	http.HandleFunc("/", handleForm)
 	http.HandleFunc("/gif/", handleGif)
 	http.HandleFunc("/check/", handleGifStatus)
+	http.HandleFunc("/events/", handleGifEvents)
+	http.HandleFunc("/cancel/", handleGifCancel)
 	http.Handle("/static/", http.StripPrefix("/static/", fs))
+	if os.Getenv("BLOB_STORE") == "local" {
+		// Serve finished GIFs straight from the local blob store
+		http.Handle("/blobs/", http.StripPrefix("/blobs/", http.FileServer(http.Dir(blobLocalDir))))
+	}
 	http.ListenAndServe(":"+port, nil)
 }
 
@@ -88,24 +101,15 @@ func handleForm(w http.ResponseWriter, r *http.Request) {
 		// Get the form info, verify, and pass on
 		var formErrors = []string{}
 		var gifName string
-		var mascotType pb.Product
+		var sceneId string
 		r.ParseForm()
 		if (r.Form["name"] != nil) && (len(r.Form["name"][0]) > 0) {
 			gifName = r.Form["name"][0]
 		} else {
 			formErrors = append(formErrors, "Please provide a name")
 		}
-		if r.Form["mascot"] != nil {
-			switch r.Form["mascot"][0] {
-			case "go":
-				mascotType = pb.Product_GO
-			case "grpc":
-				mascotType = pb.Product_GRPC
-			case "kubernetes":
-				mascotType = pb.Product_KUBERNETES
-			default:
-				mascotType = pb.Product_UNKNOWN_PRODUCT
-			}
+		if (r.Form["mascot"] != nil) && (len(r.Form["mascot"][0]) > 0) {
+			sceneId = r.Form["mascot"][0]
 		} else {
 			formErrors = append(formErrors, "Please specify a mascot")
 		}
@@ -113,45 +117,147 @@ func handleForm(w http.ResponseWriter, r *http.Request) {
 			renderForm(w, formErrors)
 			return
 		}
+		// Drafts cost extra renders, so they are only made when asked for
+		preview := r.Form.Get("preview") != ""
 		// Submit answers, get task ID, and redirect...
 		span := traceClient.NewSpan("/memecreate") // TODO(jbd): make /memcreate top-level span optional
 		defer span.Finish()
 		response, err :=
 			gcClient.StartJob(trace.NewContext(context.Background(), span),
-				&pb.StartJobRequest{Name: gifName, ProductToPlug: mascotType})
+				&pb.StartJobRequest{Name: gifName, SceneId: sceneId, Preview: preview})
+		if grpc.Code(err) == codes.InvalidArgument {
+			renderForm(w, []string{grpc.ErrorDesc(err)})
+			return
+		}
 		if err != nil {
 			// TODO(jessup) Swap these out for proper logging
 			fmt.Fprintf(os.Stderr, "cannot request Gif - %v", err)
 			return
 		}
-		http.Redirect(w, r, "/gif/"+response.JobId, 301)
+		// Keep the owner token in a cookie, so that this browser can cancel
+		// the job later. Other sites' forms can't make the browser send it.
+		http.SetCookie(w, &http.Cookie{
+			Name:     ownerCookieName(response.JobId),
+			Value:    response.OwnerToken,
+			Path:     "/",
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+		http.Redirect(w, r, "/gif/"+response.JobId, 303)
 		return
 	}
 	renderForm(w, nil)
 	return
 }
 
+type formPageData struct {
+	Errors []string
+	Scenes []*pb.Scene
+}
+
 func renderForm(w http.ResponseWriter, errors []string) {
+	// Offer whichever scenes the gifcreator has in its catalog
+	scenes, err := gcClient.ListScenes(context.Background(), &pb.ListScenesRequest{})
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
 	// Show the form
 	formPath := filepath.Join(templatePath, "form.html")
 	layoutPath := filepath.Join(templatePath, "layout.html")
 
 	t, err := template.ParseFiles(layoutPath, formPath)
 	if err == nil {
-		t.ExecuteTemplate(w, "layout", errors)
+		t.ExecuteTemplate(w, "layout", formPageData{Errors: errors, Scenes: scenes.Scenes})
 	} else {
 		http.Error(w, err.Error(), 500)
 	}
 }
 
+// jobIdPattern matches the random job IDs handed out by the gifcreator, as
+// well as the numeric IDs of jobs started before those.
+var jobIdPattern = regexp.MustCompile(`^([0-9]+|[a-z2-7]{26})$`)
+
+// jobIdFromPath extracts the job ID from paths such as /gif/<id>.
+func jobIdFromPath(path string) (string, bool) {
+	pathSegments := strings.Split(path, "/")
+	if len(pathSegments) < 3 || !jobIdPattern.MatchString(pathSegments[2]) {
+		return "", false
+	}
+	return pathSegments[2], true
+}
+
+func ownerCookieName(jobId string) string {
+	return "owner_" + jobId
+}
+
+// sameOrigin reports whether a request came from one of this site's own
+// pages, going by its Origin header, or its Referer if it has no Origin.
+// Requests with neither are let through, and are left to the owner cookie's
+// SameSite attribute.
+func sameOrigin(r *http.Request) bool {
+	from := r.Header.Get("Origin")
+	if from == "" {
+		from = r.Header.Get("Referer")
+	}
+	if from == "" {
+		return true
+	}
+	u, err := url.Parse(from)
+	return err == nil && u.Host == r.Host
+}
+
+// ownerToken returns the owner token this browser holds for a job, if any.
+func ownerToken(r *http.Request, jobId string) string {
+	cookie, err := r.Cookie(ownerCookieName(jobId))
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
 type responsePageData struct {
-	ImageId  string
-	ImageUrl string
+	ImageId   string
+	ImageUrl  string
+	Error     *jobErrorData
+	Cancelled bool
+	CanCancel bool
+}
+
+type jobErrorData struct {
+	Stage   string
+	Message string
+}
+
+// describeJobError turns a JobError into something fit to show a user.
+func describeJobError(jobErr *pb.JobError) *jobErrorData {
+	if jobErr == nil {
+		return &jobErrorData{Stage: "making your GIF"}
+	}
+	var stage string
+	switch jobErr.Stage {
+	case pb.JobError_TRANSFORM_TEMPLATES:
+		stage = "preparing the scene"
+	case pb.JobError_UPLOAD_ASSETS:
+		stage = "uploading the scene"
+	case pb.JobError_UPLOAD_BADGE:
+		stage = "making your badge"
+	case pb.JobError_RENDER_FRAME:
+		stage = fmt.Sprintf("rendering frame %d", jobErr.Frame)
+	case pb.JobError_COMPILE_GIF:
+		stage = "putting the frames together"
+	case pb.JobError_STITCH_TILES:
+		stage = fmt.Sprintf("putting frame %d together", jobErr.Frame)
+	default:
+		stage = "making your GIF"
+	}
+	return &jobErrorData{Stage: stage, Message: jobErr.Message}
 }
 
 func handleGif(w http.ResponseWriter, r *http.Request) {
-	pathSegments := strings.Split(r.URL.Path, "/")
-	if len(pathSegments) < 2 {
+	jobId, ok := jobIdFromPath(r.URL.Path)
+	if !ok {
 		http.Error(w, "Can't find the GIF ID", 404)
 		return
 	}
@@ -160,7 +266,11 @@ func handleGif(w http.ResponseWriter, r *http.Request) {
 	response, err :=
 		gcClient.GetJob(
 			context.Background(),
-			&pb.GetJobRequest{JobId: pathSegments[2]})
+			&pb.GetJobRequest{JobId: jobId})
+	if grpc.Code(err) == codes.NotFound {
+		http.Error(w, "Can't find the GIF", 404)
+		return
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "cannot get status of gif - %v", err)
 		return
@@ -168,7 +278,8 @@ func handleGif(w http.ResponseWriter, r *http.Request) {
 
 	var bodyHtmlPath string
 	var gifInfo = responsePageData{
-		ImageId: pathSegments[2],
+		ImageId:   jobId,
+		CanCancel: ownerToken(r, jobId) != "",
 	}
 	switch response.Status {
 	case pb.GetJobResponse_PENDING:
@@ -178,6 +289,14 @@ func handleGif(w http.ResponseWriter, r *http.Request) {
 		bodyHtmlPath = filepath.Join(templatePath, "gif.html")
 		gifInfo.ImageUrl = response.ImageUrl
 		break
+	case pb.GetJobResponse_FAILED:
+		bodyHtmlPath = filepath.Join(templatePath, "error.html")
+		gifInfo.Error = describeJobError(response.Error)
+		break
+	case pb.GetJobResponse_CANCELLED:
+		bodyHtmlPath = filepath.Join(templatePath, "error.html")
+		gifInfo.Cancelled = true
+		break
 	default:
 		bodyHtmlPath = filepath.Join(templatePath, "error.html")
 		break
@@ -193,23 +312,162 @@ func handleGif(w http.ResponseWriter, r *http.Request) {
 }
 
 func handleGifStatus(w http.ResponseWriter, r *http.Request) {
-	pathSegments := strings.Split(r.URL.Path, "/")
-	if len(pathSegments) < 2 {
+	jobId, ok := jobIdFromPath(r.URL.Path)
+	if !ok {
 		http.Error(w, "Can't find the GIF ID", 404)
 		return
 	}
 
-	// TODO(jessup) Need stronger input validation here.
 	response, err :=
 		gcClient.GetJob(
 			context.Background(),
-			&pb.GetJobRequest{JobId: pathSegments[2]})
+			&pb.GetJobRequest{JobId: jobId})
 	if err != nil {
 		// TODO(jessup) Swap these out for proper logging
 		fmt.Fprintf(os.Stderr, "cannot get status of gif - %v", err)
 		return
 	}
 
+	// The response carries the job's progress, which the spinner page
+	// turns into a progress bar.
 	jsonReponse, _ := json.Marshal(response)
+	w.Header().Set("Content-Type", "application/json")
 	fmt.Fprintf(w, string(jsonReponse))
 }
+
+/**
+ * handleGifEvents streams the state of a job to the browser as Server-Sent
+ * Events: a "progress" event while the job is pending, then a single "done",
+ * "failed" or "cancelled" event. Each event carries the GetJobResponse as JSON. The
+ * events come from the gifcreator's WatchJob stream, or from polling GetJob
+ * if the gifcreator is too old to have WatchJob.
+ */
+func handleGifEvents(w http.ResponseWriter, r *http.Request) {
+	jobId, ok := jobIdFromPath(r.URL.Path)
+	if !ok {
+		http.Error(w, "Can't find the GIF ID", 404)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", 500)
+		return
+	}
+	ctx := r.Context()
+
+	stream, err := gcClient.WatchJob(ctx, &pb.WatchJobRequest{JobId: jobId})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cannot watch gif - %v\n", err)
+		http.Error(w, "Can't watch the GIF", 502)
+		return
+	}
+	response, err := stream.Recv()
+	if grpc.Code(err) == codes.Unimplemented {
+		startEventStream(w)
+		pollGifEvents(ctx, w, flusher, jobId)
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cannot watch gif - %v\n", err)
+		http.Error(w, "Can't watch the GIF", 502)
+		return
+	}
+
+	startEventStream(w)
+	for {
+		writeGifEvent(w, response)
+		flusher.Flush()
+		response, err = stream.Recv()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			// The browser reconnects by itself once the response ends
+			fmt.Fprintf(os.Stderr, "lost watch on gif %s - %v\n", jobId, err)
+			return
+		}
+	}
+}
+
+// pollGifEvents stands in for WatchJob on gifcreators that don't have it,
+// sending an event whenever GetJob reports something new.
+func pollGifEvents(ctx context.Context, w http.ResponseWriter, flusher http.Flusher, jobId string) {
+	var last []byte
+	for {
+		response, err := gcClient.GetJob(ctx, &pb.GetJobRequest{JobId: jobId})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cannot get status of gif - %v\n", err)
+			return
+		}
+		current, _ := json.Marshal(response)
+		if !bytes.Equal(current, last) {
+			writeGifEvent(w, response)
+			flusher.Flush()
+			last = current
+		}
+		if response.Status != pb.GetJobResponse_PENDING {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func startEventStream(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(200)
+}
+
+func writeGifEvent(w http.ResponseWriter, response *pb.GetJobResponse) {
+	var event string
+	switch response.Status {
+	case pb.GetJobResponse_DONE:
+		event = "done"
+	case pb.GetJobResponse_FAILED:
+		event = "failed"
+	case pb.GetJobResponse_CANCELLED:
+		event = "cancelled"
+	default:
+		event = "progress"
+	}
+	data, _ := json.Marshal(response)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+}
+
+// handleGifCancel cancels a job on behalf of the browser that started it.
+func handleGifCancel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", 405)
+		return
+	}
+	if !sameOrigin(r) {
+		http.Error(w, "GIFs can only be cancelled from this site", 403)
+		return
+	}
+	jobId, ok := jobIdFromPath(r.URL.Path)
+	if !ok {
+		http.Error(w, "Can't find the GIF ID", 404)
+		return
+	}
+	_, err := gcClient.CancelJob(r.Context(), &pb.CancelJobRequest{
+		JobId:      jobId,
+		OwnerToken: ownerToken(r, jobId),
+	})
+	switch grpc.Code(err) {
+	case codes.OK:
+		http.Redirect(w, r, "/gif/"+jobId, 303)
+	case codes.PermissionDenied:
+		http.Error(w, "That's not your GIF to cancel", 403)
+	case codes.NotFound:
+		http.Error(w, "Can't find the GIF", 404)
+	case codes.FailedPrecondition:
+		http.Redirect(w, r, "/gif/"+jobId, 303)
+	default:
+		fmt.Fprintf(os.Stderr, "cannot cancel gif - %v\n", err)
+		http.Error(w, "Can't cancel the GIF", 500)
+	}
+}
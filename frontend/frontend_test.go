@@ -0,0 +1,185 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	pb "github.com/GoogleCloudPlatform/gifinator/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// fakeGifCreator answers WatchJob and GetJob from canned responses, and
+// records the owner tokens CancelJob is called with.
+type fakeGifCreator struct {
+	pb.GifCreatorClient
+	watch     []*pb.GetJobResponse
+	watchErr  error // returned once watch runs out, io.EOF if nil
+	get       []*pb.GetJobResponse
+	cancelled []string
+}
+
+func (f *fakeGifCreator) WatchJob(ctx context.Context, in *pb.WatchJobRequest, opts ...grpc.CallOption) (pb.GifCreator_WatchJobClient, error) {
+	return &fakeWatchStream{f: f}, nil
+}
+
+func (f *fakeGifCreator) GetJob(ctx context.Context, in *pb.GetJobRequest, opts ...grpc.CallOption) (*pb.GetJobResponse, error) {
+	response := f.get[0]
+	if len(f.get) > 1 {
+		f.get = f.get[1:]
+	}
+	return response, nil
+}
+
+func (f *fakeGifCreator) CancelJob(ctx context.Context, in *pb.CancelJobRequest, opts ...grpc.CallOption) (*pb.CancelJobResponse, error) {
+	f.cancelled = append(f.cancelled, in.OwnerToken)
+	return &pb.CancelJobResponse{}, nil
+}
+
+type fakeWatchStream struct {
+	grpc.ClientStream
+	f *fakeGifCreator
+}
+
+func (s *fakeWatchStream) Recv() (*pb.GetJobResponse, error) {
+	if len(s.f.watch) == 0 {
+		if s.f.watchErr != nil {
+			return nil, s.f.watchErr
+		}
+		return nil, io.EOF
+	}
+	response := s.f.watch[0]
+	s.f.watch = s.f.watch[1:]
+	return response, nil
+}
+
+func TestDescribeJobError(t *testing.T) {
+	tests := []struct {
+		jobErr *pb.JobError
+		want   jobErrorData
+	}{
+		{nil, jobErrorData{Stage: "making your GIF"}},
+		{&pb.JobError{}, jobErrorData{Stage: "making your GIF"}},
+		{&pb.JobError{Stage: pb.JobError_TRANSFORM_TEMPLATES, Message: "bad template"},
+			jobErrorData{Stage: "preparing the scene", Message: "bad template"}},
+		{&pb.JobError{Stage: pb.JobError_UPLOAD_ASSETS}, jobErrorData{Stage: "uploading the scene"}},
+		{&pb.JobError{Stage: pb.JobError_UPLOAD_BADGE}, jobErrorData{Stage: "making your badge"}},
+		{&pb.JobError{Stage: pb.JobError_RENDER_FRAME, Frame: 7, Message: "failed 5 times"},
+			jobErrorData{Stage: "rendering frame 7", Message: "failed 5 times"}},
+		{&pb.JobError{Stage: pb.JobError_COMPILE_GIF}, jobErrorData{Stage: "putting the frames together"}},
+		{&pb.JobError{Stage: 99, Message: "new stage"}, jobErrorData{Stage: "making your GIF", Message: "new stage"}},
+	}
+	for _, tt := range tests {
+		if got := describeJobError(tt.jobErr); *got != tt.want {
+			t.Errorf("describeJobError(%v) = %+v, want %+v", tt.jobErr, *got, tt.want)
+		}
+	}
+}
+
+func TestHandleGifEvents(t *testing.T) {
+	pending := &pb.GetJobResponse{Status: pb.GetJobResponse_PENDING}
+	rendering := &pb.GetJobResponse{Status: pb.GetJobResponse_PENDING, Progress: &pb.JobProgress{FramesCompleted: 3}}
+	done := &pb.GetJobResponse{Status: pb.GetJobResponse_DONE, ImageUrl: "/blobs/out.gif"}
+	failed := &pb.GetJobResponse{Status: pb.GetJobResponse_FAILED}
+	cancelled := &pb.GetJobResponse{Status: pb.GetJobResponse_CANCELLED}
+	tests := []struct {
+		desc   string
+		gc     fakeGifCreator
+		code   int
+		events []string
+	}{
+		{"watched to completion", fakeGifCreator{watch: []*pb.GetJobResponse{pending, rendering, done}},
+			200, []string{"progress", "progress", "done"}},
+		{"watched to failure", fakeGifCreator{watch: []*pb.GetJobResponse{pending, failed}},
+			200, []string{"progress", "failed"}},
+		{"watched to cancellation", fakeGifCreator{watch: []*pb.GetJobResponse{rendering, cancelled}},
+			200, []string{"progress", "cancelled"}},
+		{"watch lost", fakeGifCreator{watch: []*pb.GetJobResponse{pending}, watchErr: grpc.Errorf(codes.Unavailable, "gone")},
+			200, []string{"progress"}},
+		{"unknown job", fakeGifCreator{watchErr: grpc.Errorf(codes.NotFound, "no such job")},
+			502, nil},
+		{"polled", fakeGifCreator{watchErr: grpc.Errorf(codes.Unimplemented, "old gifcreator"), get: []*pb.GetJobResponse{done}},
+			200, []string{"done"}},
+	}
+	for _, tt := range tests {
+		gc := tt.gc
+		gcClient = &gc
+		w := httptest.NewRecorder()
+		handleGifEvents(w, httptest.NewRequest("GET", "/events/1", nil))
+		if w.Code != tt.code {
+			t.Errorf("%s: status %d, want %d", tt.desc, w.Code, tt.code)
+			continue
+		}
+		if tt.code != 200 {
+			continue
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+			t.Errorf("%s: Content-Type %q", tt.desc, ct)
+		}
+		var events []string
+		for _, line := range strings.Split(w.Body.String(), "\n") {
+			if strings.HasPrefix(line, "event: ") {
+				events = append(events, strings.TrimPrefix(line, "event: "))
+			}
+		}
+		if strings.Join(events, ",") != strings.Join(tt.events, ",") {
+			t.Errorf("%s: events %v, want %v", tt.desc, events, tt.events)
+		}
+	}
+}
+
+func TestHandleGifCancel(t *testing.T) {
+	tests := []struct {
+		desc    string
+		origin  string
+		referer string
+		code    int
+	}{
+		{"same origin", "http://gifinator.example", "", 303},
+		{"same referer", "", "http://gifinator.example/gif/1", 303},
+		{"neither", "", "", 303},
+		{"other origin", "http://evil.example", "http://gifinator.example/gif/1", 403},
+		{"other referer", "", "http://evil.example/page", 403},
+		{"opaque origin", "null", "", 403},
+	}
+	for _, tt := range tests {
+		gc := &fakeGifCreator{}
+		gcClient = gc
+		r := httptest.NewRequest("POST", "http://gifinator.example/cancel/1", nil)
+		r.AddCookie(&http.Cookie{Name: ownerCookieName("1"), Value: "secret"})
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		if tt.referer != "" {
+			r.Header.Set("Referer", tt.referer)
+		}
+		w := httptest.NewRecorder()
+		handleGifCancel(w, r)
+		if w.Code != tt.code {
+			t.Errorf("%s: status %d, want %d", tt.desc, w.Code, tt.code)
+		}
+		if cancelled := len(gc.cancelled) == 1; cancelled != (tt.code == 303) {
+			t.Errorf("%s: cancelled %v", tt.desc, gc.cancelled)
+		}
+	}
+}
@@ -14,23 +14,84 @@
  * limitations under the License.
  */
 
+/**
+ *  Frontend_watchJob
+ *  Will follow the target job as it progresses, calling back on every update
+ *  until the job is done or has failed. Updates are streamed from /events/,
+ *  falling back to polling /check/ if the browser or the server can't stream.
+ *    @job_id       string    The ID of the job
+ *    @on_update    function  Callback to exectute whenever the job changes.
+ *                            The callback takes three parameters:
+ *                                 @status   1=pending, 2=done, 3=failed,
+ *                                           4=cancelled
+ *                                 @err      null or the error object
+ *                                 @job      the job as returned by /check/
+ */
+
+function Frontend_watchJob(job_id, on_update) {
+  if (typeof(EventSource) == "undefined") {
+    Frontend_pollJob(job_id, on_update);
+    return;
+  }
+  var source = new EventSource("/events/"+job_id);
+  var received = false;
+  var handler = function(e) {
+    received = true;
+    var job = JSON.parse(e.data);
+    if (job.status != 1) {
+      source.close();
+    }
+    on_update(job.status, null, job);
+  };
+  source.addEventListener("progress", handler);
+  source.addEventListener("done", handler);
+  source.addEventListener("failed", handler);
+  source.addEventListener("cancelled", handler);
+  source.onerror = function() {
+    // The browser reconnects by itself if a stream that was working drops,
+    // but a stream that never got going is not coming back.
+    if (!received || source.readyState == EventSource.CLOSED) {
+      source.close();
+      Frontend_pollJob(job_id, on_update);
+    }
+  };
+}
+
+/**
+ *  Frontend_pollJob
+ *  Polls the backend once a second until the target job is done or has
+ *  failed, calling back with every status it sees.
+ *    @job_id       string    The ID of the job
+ *    @on_update    function  Callback as for Frontend_watchJob
+ */
+
+function Frontend_pollJob(job_id, on_update) {
+  var retryIntervalMs = 1000;
+  var check = function() {
+    Frontend_checkJob(job_id, function(status, err, job) {
+      on_update(status, err, job);
+      if (status == 1) {
+        setTimeout(check, retryIntervalMs);
+      }
+    });
+  };
+  check();
+}
+
 /**
  *  Frontend_checkJob
- *  Will poll the backend to see if the target job has been completed, and if
- *  so trigger a reload of the page (which should show the completed gif)
+ *  Will ask the backend once for the status of the target job.
  *    @job_id       string    The ID of the job
- *    @on_complete  function  Callback to exectute once the job's final status
- *                            has been determined. The callback takes two
- *                            parameters:
- *                                 @status 0=failed, 1=succeeded, 2=waiting
- *                                 @err    null or the error object if status=0
+ *    @on_complete  function  Callback to exectute once the job's status has
+ *                            been retrieved. The callback takes the same
+ *                            parameters as for Frontend_watchJob.
  */
 
 function Frontend_checkJob(job_id, on_complete) {
   _getRemoteJson("/check/"+job_id, function(http_status, data){
     if(http_status==200){
       if(data.status != null) {
-        on_complete(data.status, null);
+        on_complete(data.status, null, data);
       }else{
         alert('Error retrieving status.');
       }
@@ -66,3 +127,26 @@ function _getRemoteJson(uri, on_complete) {
   xmlhttp.open("GET", uri, true);
   xmlhttp.send();
 }
+
+/**
+ *  Frontend_describeProgress
+ *  Turns the progress reported by /check/ into a sentence for the user
+ *
+ *    @progress     object    The progress field of a GetJobResponse
+ */
+
+function Frontend_describeProgress(progress) {
+  var completed = progress.frames_completed || 0;
+  var total = progress.frames_total || 0;
+  switch(progress.stage) {
+    case 1:
+      return "Preparing the scene...";
+    case 2:
+      return "Rendered "+completed+" of "+total+" frames...";
+    case 3:
+      return "Putting the frames together...";
+    case 4:
+      return "Uploading your GIF...";
+  }
+  return "Getting started...";
+}
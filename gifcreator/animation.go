@@ -0,0 +1,107 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"math"
+
+	pb "github.com/GoogleCloudPlatform/gifinator/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	defaultFrameCount = 15
+	defaultStartAngle = 20
+	defaultEndAngle   = 48
+)
+
+// Server-side limits on the animation a job can ask for. Every frame costs a
+// render, so the frame count is what keeps jobs affordable.
+var (
+	maxFrameCount      = 60
+	maxFramesPerSecond = 50
+)
+
+// animation describes the frames of a job's GIF. A FramesPerSecond of 0
+// leaves the frames without a delay.
+type animation struct {
+	FrameCount      int
+	StartAngle      float32
+	EndAngle        float32
+	FramesPerSecond int
+}
+
+// animationFor fills in the defaults for a StartJobRequest, and checks the
+// result against the server's limits.
+func animationFor(req *pb.StartJobRequest) (animation, error) {
+	a := animation{
+		FrameCount:      int(req.FrameCount),
+		StartAngle:      req.StartAngle,
+		EndAngle:        req.EndAngle,
+		FramesPerSecond: int(req.FramesPerSecond),
+	}
+	if a.FrameCount == 0 {
+		a.FrameCount = defaultFrameCount
+	}
+	if a.StartAngle == 0 && a.EndAngle == 0 {
+		a.StartAngle = defaultStartAngle
+		a.EndAngle = defaultEndAngle
+	}
+
+	if a.FrameCount < 1 || a.FrameCount > maxFrameCount {
+		return a, grpc.Errorf(codes.InvalidArgument, "frame count must be between 1 and %d", maxFrameCount)
+	}
+	if a.FramesPerSecond < 0 || a.FramesPerSecond > maxFramesPerSecond {
+		return a, grpc.Errorf(codes.InvalidArgument, "frames per second must be between 0 and %d", maxFramesPerSecond)
+	}
+	for _, angle := range []float32{a.StartAngle, a.EndAngle} {
+		if math.IsNaN(float64(angle)) || angle < -360 || angle > 360 {
+			return a, grpc.Errorf(codes.InvalidArgument, "angles must be between -360 and 360 degrees")
+		}
+	}
+	return a, nil
+}
+
+// rotation returns the angle of the mascot in the given frame.
+func (a animation) rotation(frame int) float32 {
+	if a.FrameCount == 1 {
+		return a.StartAngle
+	}
+	step := (a.EndAngle - a.StartAngle) / float32(a.FrameCount-1)
+	return a.StartAngle + step*float32(frame)
+}
+
+// delay returns the time between frames, in the 100ths of a second that GIF
+// uses.
+func (a animation) delay() int {
+	if a.FramesPerSecond == 0 {
+		return 0
+	}
+	return int(math.Floor(100/float64(a.FramesPerSecond) + 0.5))
+}
+
+// framePrefix is the name that the rendered frames of a job start with. The
+// frame number is zero padded, so listing the frames returns them in order.
+func framePrefix(jobIdStr string) string {
+	return "out." + jobIdStr + "/frame_"
+}
+
+func frameName(jobIdStr string, frame int64) string {
+	return fmt.Sprintf("%s%04d", framePrefix(jobIdStr), frame)
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"math"
+	"testing"
+
+	pb "github.com/GoogleCloudPlatform/gifinator/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func TestAnimationFor(t *testing.T) {
+	nan := float32(math.NaN())
+	tests := []struct {
+		desc string
+		req  pb.StartJobRequest
+		want animation
+		code codes.Code
+	}{
+		{"defaults", pb.StartJobRequest{},
+			animation{FrameCount: 15, StartAngle: 20, EndAngle: 48, FramesPerSecond: 0}, codes.OK},
+		{"everything set", pb.StartJobRequest{FrameCount: 30, StartAngle: -90, EndAngle: 90, FramesPerSecond: 25},
+			animation{FrameCount: 30, StartAngle: -90, EndAngle: 90, FramesPerSecond: 25}, codes.OK},
+		{"one angle set", pb.StartJobRequest{EndAngle: 180},
+			animation{FrameCount: 15, StartAngle: 0, EndAngle: 180, FramesPerSecond: 0}, codes.OK},
+		{"held still", pb.StartJobRequest{StartAngle: 360, EndAngle: 360},
+			animation{FrameCount: 15, StartAngle: 360, EndAngle: 360, FramesPerSecond: 0}, codes.OK},
+		{"at the limits", pb.StartJobRequest{FrameCount: 60, StartAngle: -360, EndAngle: 360, FramesPerSecond: 50},
+			animation{FrameCount: 60, StartAngle: -360, EndAngle: 360, FramesPerSecond: 50}, codes.OK},
+		{"too many frames", pb.StartJobRequest{FrameCount: 61}, animation{}, codes.InvalidArgument},
+		{"negative frames", pb.StartJobRequest{FrameCount: -1}, animation{}, codes.InvalidArgument},
+		{"too fast", pb.StartJobRequest{FramesPerSecond: 51}, animation{}, codes.InvalidArgument},
+		{"negative rate", pb.StartJobRequest{FramesPerSecond: -10}, animation{}, codes.InvalidArgument},
+		{"angle too large", pb.StartJobRequest{StartAngle: 0, EndAngle: 361}, animation{}, codes.InvalidArgument},
+		{"angle too small", pb.StartJobRequest{StartAngle: -361, EndAngle: 0}, animation{}, codes.InvalidArgument},
+		{"NaN angle", pb.StartJobRequest{StartAngle: nan, EndAngle: 10}, animation{}, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		req := tt.req
+		got, err := animationFor(&req)
+		if code := grpc.Code(err); code != tt.code {
+			t.Errorf("%s: animationFor = %v, want %v", tt.desc, err, tt.code)
+			continue
+		}
+		if err == nil && got != tt.want {
+			t.Errorf("%s: animationFor = %+v, want %+v", tt.desc, got, tt.want)
+		}
+	}
+}
+
+func TestAnimationFrames(t *testing.T) {
+	tests := []struct {
+		a         animation
+		rotations []float32
+		delay     int
+	}{
+		{animation{FrameCount: 1, StartAngle: 30, EndAngle: 90, FramesPerSecond: 1}, []float32{30}, 100},
+		{animation{FrameCount: 3, StartAngle: 20, EndAngle: 48}, []float32{20, 34, 48}, 0},
+		{animation{FrameCount: 3, StartAngle: 20, EndAngle: 48, FramesPerSecond: 10}, []float32{20, 34, 48}, 10},
+		{animation{FrameCount: 5, StartAngle: 90, EndAngle: -90, FramesPerSecond: 30}, []float32{90, 45, 0, -45, -90}, 3},
+		{animation{FrameCount: 2, StartAngle: 0, EndAngle: 360, FramesPerSecond: 50}, []float32{0, 360}, 2},
+	}
+	for _, tt := range tests {
+		for frame, want := range tt.rotations {
+			if got := tt.a.rotation(frame); got != want {
+				t.Errorf("%+v: rotation(%d) = %v, want %v", tt.a, frame, got, want)
+			}
+		}
+		if got := tt.a.delay(); got != tt.delay {
+			t.Errorf("%+v: delay() = %d, want %d", tt.a, got, tt.delay)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/gifinator/internal/blobstore"
+	"github.com/GoogleCloudPlatform/gifinator/internal/gcsref"
+	"golang.org/x/net/context"
+)
+
+/**
+ * Each scene manifest lists the assets its materials need, as files in
+ * SCENE_PATH. The server copies any that are missing from the bucket, or
+ * whose contents differ, when it starts. A render is sent only the textures that
+ * the job's materials name on their map_Kd lines, rather than every texture
+ * that any scene might use.
+ */
+
+// mtlTextures returns the texture files named on the map_Kd lines of a
+// material library, in the order they first appear.
+func mtlTextures(r io.Reader) ([]string, error) {
+	var textures []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "map_Kd" {
+			continue
+		}
+		// Options come before the file name
+		texture := fields[len(fields)-1]
+		if !seen[texture] {
+			seen[texture] = true
+			textures = append(textures, texture)
+		}
+	}
+	return textures, scanner.Err()
+}
+
+// checkSceneAssets makes sure that every texture a scene's material template
+// names is either declared as one of its assets, or made per job.
+func checkSceneAssets(dir string, m *sceneManifest) error {
+	f, err := os.Open(filepath.Join(dir, m.MtlTemplate))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	textures, err := mtlTextures(f)
+	if err != nil {
+		return err
+	}
+	declared := make(map[string]bool)
+	for _, asset := range m.Assets {
+		declared[asset] = true
+	}
+	for _, texture := range textures {
+		if strings.Contains(texture, "{{") {
+			// Templated textures, like the badge, are uploaded by StartJob
+			continue
+		}
+		if !declared[texture] {
+			return fmt.Errorf("scene %s: %s uses %s, which is not one of its assets", m.Id, m.MtlTemplate, texture)
+		}
+	}
+	return nil
+}
+
+// syncSceneAssets uploads the assets of every scene in the catalog that are
+// missing from the bucket or out of date.
+func syncSceneAssets(ctx context.Context, dir string) error {
+	synced := make(map[string]bool)
+	for _, m := range sceneCatalog {
+		for _, asset := range m.Assets {
+			if synced[asset] {
+				continue
+			}
+			uploaded, err := syncAsset(ctx, filepath.Join(dir, asset), blobPath(asset))
+			if err != nil {
+				return fmt.Errorf("scene %s: asset %s: %v", m.Id, asset, err)
+			}
+			if uploaded {
+				fmt.Fprintf(os.Stdout, "uploaded scene asset %s\n", asset)
+			}
+			synced[asset] = true
+		}
+	}
+	return nil
+}
+
+// syncAsset copies a local file to the blob store, unless the same file is
+// already there.
+func syncAsset(ctx context.Context, localPath string, outputPath string) (bool, error) {
+	ref, err := gcsref.ParseRef(outputPath)
+	if err != nil {
+		return false, err
+	}
+	f, err := os.Open(localPath)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+	attrs, err := blobStore.Stat(ctx, ref)
+	if err != nil && err != blobstore.ErrNotExist {
+		return false, err
+	}
+	if err == nil {
+		current, err := matchesLocal(f, info.Size(), attrs)
+		if err != nil || current {
+			return false, err
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return false, err
+		}
+	}
+	contentType := mime.TypeByExtension(filepath.Ext(localPath))
+	if contentType == "" {
+		contentType = "binary/octet-stream"
+	}
+	return true, blobstore.Upload(ctx, blobStore, ref, f, contentType)
+}
+
+// matchesLocal reports whether a stored object has the same contents as a
+// local file, comparing MD5 hashes if the store keeps them and sizes if not.
+func matchesLocal(f *os.File, size int64, attrs *blobstore.Attrs) (bool, error) {
+	if attrs.Size != size {
+		return false, nil
+	}
+	if attrs.MD5 == nil {
+		return true, nil
+	}
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return false, err
+	}
+	return bytes.Equal(h.Sum(nil), attrs.MD5), nil
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/gifinator/internal/blobstore"
+	"github.com/GoogleCloudPlatform/gifinator/internal/gcsref"
+	"golang.org/x/net/context"
+)
+
+func TestMtlTextures(t *testing.T) {
+	tests := []struct {
+		mtl  string
+		want []string
+	}{
+		{"", nil},
+		{"newmtl plain\nKd 1 1 1\n", nil},
+		{"newmtl a\nmap_Kd a.png\n", []string{"a.png"}},
+		{"newmtl a\nmap_Kd -blendu on -s 1 1 1 a.png\n", []string{"a.png"}},
+		{"newmtl a\nmap_Kd a.png\nnewmtl b\nmap_Kd b.png\nnewmtl c\nmap_Kd a.png\n", []string{"a.png", "b.png"}},
+		{"newmtl badge\n  map_Kd job_{{.}}_badge.png\n", []string{"job_{{.}}_badge.png"}},
+		{"newmtl a\nmap_Ks shiny.png\nmap_Kd\n# map_Kd old.png\n", nil},
+	}
+	for _, tt := range tests {
+		got, err := mtlTextures(strings.NewReader(tt.mtl))
+		if err != nil {
+			t.Errorf("mtlTextures(%q) = %v", tt.mtl, err)
+			continue
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("mtlTextures(%q) = %v, want %v", tt.mtl, got, tt.want)
+		}
+	}
+}
+
+func TestSyncAsset(t *testing.T) {
+	dir, err := ioutil.TempDir("", "assets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(s blobstore.BlobStore) { blobStore = s }(blobStore)
+	blobStore = blobstore.NewLocal(filepath.Join(dir, "store"), "")
+	ctx := context.Background()
+	local := filepath.Join(dir, "texture.png")
+	ref := gcsref.MustParseRef("file://bucket/texture.png")
+
+	tests := []struct {
+		desc     string
+		contents string
+		uploaded bool
+	}{
+		{"missing", "pixels", true},
+		{"same size", "PIXELS", false},
+		{"new size", "more pixels", true},
+	}
+	for _, tt := range tests {
+		ioutil.WriteFile(local, []byte(tt.contents), 0666)
+		uploaded, err := syncAsset(ctx, local, ref.String())
+		if err != nil {
+			t.Fatalf("%s: %v", tt.desc, err)
+		}
+		if uploaded != tt.uploaded {
+			t.Errorf("%s: syncAsset uploaded %v, want %v", tt.desc, uploaded, tt.uploaded)
+		}
+		attrs, err := blobStore.Stat(ctx, ref)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.desc, err)
+		}
+		if attrs.ContentType != "image/png" {
+			t.Errorf("%s: stored as %q", tt.desc, attrs.ContentType)
+		}
+	}
+	if _, err := syncAsset(ctx, filepath.Join(dir, "missing.png"), ref.String()); err == nil {
+		t.Errorf("syncAsset of a missing file succeeded")
+	}
+}
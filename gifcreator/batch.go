@@ -0,0 +1,203 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	pb "github.com/GoogleCloudPlatform/gifinator/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"gopkg.in/redis.v5"
+)
+
+/**
+ * A worker that leases a frame also leases up to RENDER_BATCH_SIZE - 1 more
+ * frames of the same job, if they are next in the queue, and has them all
+ * rendered with one RenderFrames call. The render service then only fetches
+ * and loads the job's mesh once. Each frame keeps its own lease, and is
+ * completed as soon as the render service reports it written, so a batch
+ * that fails part way through only retries the frames it didn't finish.
+ */
+
+// renderBatchSize is the most frames a worker renders in one call. Set from
+// RENDER_BATCH_SIZE.
+var renderBatchSize = 1
+
+// leaseMoreTasksScript moves tasks from the end of gifjob_queued (KEYS[1]) to
+// gifjob_processing (KEYS[2]) while they belong to the job whose tasks start
+// with ARGV[1], up to ARGV[2] of them, and records the lease ARGV[3] on each in
+// gifjob_leases (KEYS[3]). Running it as a script means no other worker can
+// take a task between the check and the move.
+var leaseMoreTasksScript = redis.NewScript(`
+local leased = {}
+for i = 1, tonumber(ARGV[2]) do
+  local nextTask = redis.call("LINDEX", KEYS[1], -1)
+  if not nextTask or string.sub(nextTask, 1, string.len(ARGV[1])) ~= ARGV[1] then
+    break
+  end
+  redis.call("RPOPLPUSH", KEYS[1], KEYS[2])
+  redis.call("HSET", KEYS[3], nextTask, ARGV[3])
+  leased[#leased + 1] = nextTask
+end
+return leased
+`)
+
+// leaseMoreTasks leases up to n more tasks of a job from the end of
+// gifjob_queued, stopping at the first task of another job.
+func leaseMoreTasks(jobIdStr string, n int) ([]string, error) {
+	payload, err := json.Marshal(taskLease{WorkerId: workerId, LeasedAt: time.Now()})
+	if err != nil {
+		return nil, err
+	}
+	keys := []string{"gifjob_queued", "gifjob_processing", "gifjob_leases"}
+	result, err := leaseMoreTasksScript.Run(redisClient, keys, jobIdStr+"_", n, string(payload)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var leased []string
+	values, _ := result.([]interface{})
+	for _, v := range values {
+		jobString, ok := v.(string)
+		if !ok {
+			return leased, fmt.Errorf("unexpected leased task %v", v)
+		}
+		fmt.Fprintf(os.Stdout, "leased gifjob_%s\n", jobString)
+		leased = append(leased, jobString)
+	}
+	return leased, nil
+}
+
+// renderBatch renders several leased tasks of a job with one RenderFrames
+// call, completing each as it is written.
+func renderBatch(tCtx context.Context, renderCtx context.Context, jobIdStr string, jobStrings []string, tasks []renderTask) error {
+	stops := make([]func(), len(tasks))
+	finished := make([]bool, len(tasks))
+	for i := range tasks {
+		stops[i] = renewLease(jobStrings[i])
+	}
+	finish := func(i int) {
+		if !finished[i] {
+			finished[i] = true
+			stops[i]()
+		}
+	}
+	defer func() {
+		for i := range tasks {
+			finish(i)
+		}
+	}()
+
+	req := &pb.RenderFramesRequest{Scene: renderRequestFor(jobIdStr, tasks[0])}
+	for _, task := range tasks {
+		req.Frames = append(req.Frames, &pb.RenderFramesRequest_Frame{
+			GcsOutputBase: outputBaseFor(jobIdStr, task),
+			Rotation:      task.Rotation,
+			Region:        task.region(),
+		})
+	}
+	// A frame that can't be completed stops the batch, and the stream with it
+	streamCtx, cancelStream := context.WithCancel(renderCtx)
+	defer cancelStream()
+	started := false
+	var trailer metadata.MD
+	var completeErr error
+	stream, err := renderClient.RenderFrames(streamCtx, req)
+	for err == nil {
+		var resp *pb.RenderFramesResponse
+		resp, err = stream.Recv()
+		if err != nil {
+			trailer = stream.Trailer()
+			break
+		}
+		started = true
+		i := int(resp.Frame)
+		if i < 0 || i >= len(tasks) || finished[i] {
+			err = fmt.Errorf("render service returned unexpected frame %d", resp.Frame)
+			break
+		}
+		finish(i)
+		completeErr = completeTask(tCtx, jobIdStr, jobStrings[i], tasks[i])
+		if completeErr != nil {
+			cancelStream()
+			err = completeErr
+			break
+		}
+	}
+	if grpc.Code(err) == codes.Unimplemented && !started {
+		// The render service is older than RenderFrames
+		return renderEach(tCtx, renderCtx, jobIdStr, jobStrings, tasks, finish)
+	}
+	if err == io.EOF {
+		err = nil
+	}
+
+	// Whatever the render service didn't get to failed along with the batch
+	firstErr := completeErr
+	for i := range tasks {
+		if finished[i] {
+			continue
+		}
+		finish(i)
+		taskErr := err
+		if taskErr == nil {
+			taskErr = fmt.Errorf("render service did not return frame %d", tasks[i].Frame)
+		}
+		if abandonErr := abandonTask(renderCtx, jobStrings[i], taskErr, trailer); firstErr == nil {
+			firstErr = abandonErr
+		}
+	}
+	return firstErr
+}
+
+// renderEach renders leased tasks one RenderFrame call at a time. Once a
+// frame can't be completed, the rest are abandoned unrendered.
+func renderEach(tCtx context.Context, renderCtx context.Context, jobIdStr string, jobStrings []string, tasks []renderTask, finish func(int)) error {
+	var firstErr, completeErr error
+	for i, task := range tasks {
+		if completeErr != nil {
+			// Its error is completeErr again, which is already returned
+			finish(i)
+			abandonTask(renderCtx, jobStrings[i], completeErr, nil)
+			continue
+		}
+		req := frameRequestFor(jobIdStr, task)
+		var trailer metadata.MD
+		_, err := renderClient.RenderFrame(renderCtx, req, grpc.Trailer(&trailer))
+		finish(i)
+		if err != nil {
+			if abandonErr := abandonTask(renderCtx, jobStrings[i], err, trailer); firstErr == nil {
+				firstErr = abandonErr
+			}
+			continue
+		}
+		completeErr = completeTask(tCtx, jobIdStr, jobStrings[i], task)
+		if completeErr != nil && firstErr == nil {
+			firstErr = completeErr
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,229 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/GoogleCloudPlatform/gifinator/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+// fakeRenderClient stands in for the render service. RenderFrames streams
+// back frames and then fails with err and trailer, or ends cleanly if err is
+// nil.
+type fakeRenderClient struct {
+	pb.RenderClient
+	frames  []int32
+	err     error
+	trailer metadata.MD
+	single  int
+	ctx     context.Context
+}
+
+func (c *fakeRenderClient) RenderFrame(ctx context.Context, req *pb.RenderRequest, opts ...grpc.CallOption) (*pb.RenderResponse, error) {
+	c.single++
+	return &pb.RenderResponse{}, nil
+}
+
+func (c *fakeRenderClient) RenderFrames(ctx context.Context, req *pb.RenderFramesRequest, opts ...grpc.CallOption) (pb.Render_RenderFramesClient, error) {
+	c.ctx = ctx
+	return &fakeFramesStream{frames: c.frames, err: c.err, trailer: c.trailer}, nil
+}
+
+type fakeFramesStream struct {
+	grpc.ClientStream
+	frames  []int32
+	err     error
+	trailer metadata.MD
+}
+
+func (s *fakeFramesStream) Trailer() metadata.MD {
+	return s.trailer
+}
+
+func (s *fakeFramesStream) Recv() (*pb.RenderFramesResponse, error) {
+	if len(s.frames) == 0 {
+		if s.err != nil {
+			return nil, s.err
+		}
+		return nil, io.EOF
+	}
+	frame := s.frames[0]
+	s.frames = s.frames[1:]
+	return &pb.RenderFramesResponse{Frame: frame}, nil
+}
+
+func TestLeaseMoreTasks(t *testing.T) {
+	tests := []struct {
+		desc   string
+		queue  []string
+		n      int
+		want   string
+		remain string
+	}{
+		{"more than wanted", []string{"2_0", "1_3", "1_2", "1_1"}, 2, "1_1,1_2", "2_0,1_3"},
+		{"another job next", []string{"1_3", "2_0", "1_1"}, 2, "1_1", "1_3,2_0"},
+		{"similar job id", []string{"11_0", "1_1"}, 2, "1_1", "11_0"},
+		{"empty queue", nil, 2, "", ""},
+	}
+	for _, tt := range tests {
+		mr, cleanup := newTestRedis(t)
+		if len(tt.queue) > 0 {
+			mr.Push("gifjob_queued", tt.queue...)
+		}
+		leased, err := leaseMoreTasks("1", tt.n)
+		if err != nil {
+			t.Errorf("%s: leaseMoreTasks = %v", tt.desc, err)
+		}
+		queued, _ := mr.List("gifjob_queued")
+		processing, _ := mr.List("gifjob_processing")
+		if strings.Join(leased, ",") != tt.want || strings.Join(queued, ",") != tt.remain {
+			t.Errorf("%s: leased %v leaving %v, want %s leaving %s", tt.desc, leased, queued, tt.want, tt.remain)
+		}
+		if len(processing) != len(leased) {
+			t.Errorf("%s: processing %v, want %v", tt.desc, processing, leased)
+		}
+		for _, jobString := range leased {
+			if mr.HGet("gifjob_leases", jobString) == "" {
+				t.Errorf("%s: no lease recorded on %s", tt.desc, jobString)
+			}
+		}
+		cleanup()
+	}
+}
+
+func TestRenderBatch(t *testing.T) {
+	defer func(c pb.RenderClient) { renderClient = c }(renderClient)
+	tests := []struct {
+		desc      string
+		frames    []int32
+		err       error
+		trailer   metadata.MD
+		completed string
+		delayed   string
+		ok        bool
+	}{
+		{"every frame", []int32{2, 0, 1}, nil, nil, "3", "", true},
+		{"failed part way", []int32{0}, errors.New("render failed"), nil, "1", "1_1,1_2", false},
+		{"refused part way", []int32{0}, grpc.Errorf(codes.ResourceExhausted, "busy"), metadata.Pairs("retry-after-ms", "60000"), "1", "1_1,1_2", false},
+		{"missing frame", []int32{0, 1}, nil, nil, "2", "1_2", false},
+		{"unexpected frame", []int32{0, 7}, nil, nil, "1", "1_1,1_2", false},
+		{"older render service", nil, grpc.Errorf(codes.Unimplemented, "unknown method"), nil, "3", "", true},
+	}
+	for _, tt := range tests {
+		mr, cleanup := newTestRedis(t)
+		mr.Set("job_gifjob_1", `{"Status":1}`)
+		mr.Set("counter_queued_gifjob_1", "10")
+		jobStrings := []string{"1_0", "1_1", "1_2"}
+		tasks := make([]renderTask, len(jobStrings))
+		for i, jobString := range jobStrings {
+			mr.Set("task_gifjob_"+jobString, `{}`)
+			mr.Push("gifjob_processing", jobString)
+			mr.HSet("gifjob_leases", jobString, `{}`)
+			tasks[i] = renderTask{Frame: int64(i)}
+		}
+		client := &fakeRenderClient{frames: tt.frames, err: tt.err, trailer: tt.trailer}
+		renderClient = client
+
+		ctx := context.Background()
+		err := renderBatch(ctx, ctx, "1", jobStrings, tasks)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: renderBatch = %v", tt.desc, err)
+		}
+		completed, _ := mr.Get("counter_completed_gifjob_1")
+		delayed, _ := mr.ZMembers("gifjob_delayed")
+		if completed != tt.completed || strings.Join(delayed, ",") != tt.delayed {
+			t.Errorf("%s: completed %s and delayed %v, want %s and %s", tt.desc, completed, delayed, tt.completed, tt.delayed)
+		}
+		if processing, _ := mr.List("gifjob_processing"); len(processing) != 0 {
+			t.Errorf("%s: %v still processing", tt.desc, processing)
+		}
+		for _, jobString := range strings.Split(tt.delayed, ",") {
+			if jobString == "" {
+				continue
+			}
+			task, _ := loadTask(jobString)
+			refused := grpc.Code(tt.err) == codes.ResourceExhausted
+			if refused != (task.Attempts == 0) {
+				t.Errorf("%s: %s has %d attempts recorded", tt.desc, jobString, task.Attempts)
+			}
+			readyAt, _ := mr.ZScore("gifjob_delayed", jobString)
+			if refused && int64(readyAt) < time.Now().Add(50*time.Second).Unix() {
+				t.Errorf("%s: %s deferred until %v, not by the retry-after-ms trailer", tt.desc, jobString, int64(readyAt))
+			}
+		}
+		if tt.err != nil && grpc.Code(tt.err) == codes.Unimplemented && client.single != len(tasks) {
+			t.Errorf("%s: rendered %d frames one at a time, want %d", tt.desc, client.single, len(tasks))
+		}
+		cleanup()
+	}
+}
+
+func TestRenderBatchCompleteError(t *testing.T) {
+	defer func(c pb.RenderClient) { renderClient = c }(renderClient)
+	tests := []struct {
+		desc string
+		err  error
+	}{
+		{"batch", nil},
+		{"one at a time", grpc.Errorf(codes.Unimplemented, "unknown method")},
+	}
+	for _, tt := range tests {
+		mr, cleanup := newTestRedis(t)
+		// Without counter_queued_gifjob_1 the first frame can't be completed
+		mr.Set("job_gifjob_1", `{"Status":1}`)
+		jobStrings := []string{"1_0", "1_1", "1_2"}
+		tasks := make([]renderTask, len(jobStrings))
+		for i, jobString := range jobStrings {
+			mr.Set("task_gifjob_"+jobString, `{}`)
+			mr.Push("gifjob_processing", jobString)
+			tasks[i] = renderTask{Frame: int64(i)}
+		}
+		client := &fakeRenderClient{frames: []int32{0, 1, 2}, err: tt.err}
+		if tt.err != nil {
+			client.frames = nil
+		}
+		renderClient = client
+
+		ctx := context.Background()
+		if err := renderBatch(ctx, ctx, "1", jobStrings, tasks); err == nil {
+			t.Errorf("%s: renderBatch succeeded", tt.desc)
+		}
+		if tt.err == nil && client.ctx.Err() == nil {
+			t.Errorf("%s: stream not cancelled", tt.desc)
+		}
+		if tt.err != nil && client.single != 1 {
+			t.Errorf("%s: rendered %d frames, want 1", tt.desc, client.single)
+		}
+		delayed, _ := mr.ZMembers("gifjob_delayed")
+		if strings.Join(delayed, ",") != "1_1,1_2" {
+			t.Errorf("%s: delayed %v, want [1_1 1_2]", tt.desc, delayed)
+		}
+		if processing, _ := mr.List("gifjob_processing"); len(processing) != 0 {
+			t.Errorf("%s: %v still processing", tt.desc, processing)
+		}
+		cleanup()
+	}
+}
@@ -0,0 +1,175 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/GoogleCloudPlatform/gifinator/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+/**
+ * Cancelling a job marks it as CANCELLED and takes its tasks off
+ * gifjob_queued, gifjob_draft_queued and gifjob_delayed. Tasks that a worker has already leased
+ * are dealt with by the worker: CancelJob publishes the job ID on the
+ * gifjob_cancelled channel, and every worker cancels the context of any
+ * RenderFrame call it has in flight for that job. A worker that leases a task
+ * of a cancelled job drops it without rendering, the same as for a failed job.
+ */
+
+const cancelChannel = "gifjob_cancelled"
+
+// inFlight maps a job ID to the cancel functions of the RenderFrame calls
+// this worker has in flight for it.
+var inFlight = struct {
+	sync.Mutex
+	nextId  int
+	renders map[string]map[int]context.CancelFunc
+}{renders: make(map[string]map[int]context.CancelFunc)}
+
+// trackRender registers the cancel function of a RenderFrame call for a job,
+// and returns a function that unregisters it.
+func trackRender(jobIdStr string, cancel context.CancelFunc) func() {
+	inFlight.Lock()
+	defer inFlight.Unlock()
+	if inFlight.renders[jobIdStr] == nil {
+		inFlight.renders[jobIdStr] = make(map[int]context.CancelFunc)
+	}
+	id := inFlight.nextId
+	inFlight.nextId++
+	inFlight.renders[jobIdStr][id] = cancel
+	return func() {
+		inFlight.Lock()
+		defer inFlight.Unlock()
+		delete(inFlight.renders[jobIdStr], id)
+		if len(inFlight.renders[jobIdStr]) == 0 {
+			delete(inFlight.renders, jobIdStr)
+		}
+	}
+}
+
+// cancelRenders cancels every RenderFrame call in flight for a job.
+func cancelRenders(jobIdStr string) {
+	inFlight.Lock()
+	defer inFlight.Unlock()
+	for _, cancel := range inFlight.renders[jobIdStr] {
+		cancel()
+	}
+}
+
+func (server) CancelJob(ctx context.Context, req *pb.CancelJobRequest) (*pb.CancelJobResponse, error) {
+	span := traceClient.NewSpan("gifCreator.CancelJob")
+	span.SetLabel("service", serviceName)
+	span.SetLabel("version", deploymentId)
+	defer span.Finish()
+
+	job, err := loadJob(req.JobId)
+	if err != nil {
+		return nil, jobNotFound(req.JobId, err)
+	}
+	err = checkOwner(req.JobId, job, req.OwnerToken)
+	if err != nil {
+		return nil, err
+	}
+	// The job may finish between the load above and here, so the status is
+	// only changed if it is still running when it is written
+	job, err = updateJob(req.JobId, func(job *renderJob) {
+		job.Status = pb.GetJobResponse_CANCELLED
+		job.EndTime = time.Now().Unix()
+	})
+	if err == errJobFinished {
+		if job.Status == pb.GetJobResponse_CANCELLED {
+			return &pb.CancelJobResponse{}, nil
+		}
+		return nil, grpc.Errorf(codes.FailedPrecondition, "job %s is already %v", req.JobId, job.Status)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = removeQueuedTasks(req.JobId)
+	if err != nil {
+		return nil, err
+	}
+	err = redisClient.Publish(cancelChannel, req.JobId).Err()
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(os.Stdout, "cancelled job_gifjob_%s\n", req.JobId)
+	return &pb.CancelJobResponse{}, nil
+}
+
+// removeQueuedTasks takes the tasks of a job off gifjob_queued,
+// gifjob_draft_queued and gifjob_delayed, so that no worker leases them.
+func removeQueuedTasks(jobIdStr string) error {
+	for _, queue := range []string{"gifjob_queued", draftQueue} {
+		queued, err := redisClient.LRange(queue, 0, -1).Result()
+		if err != nil {
+			return err
+		}
+		for _, jobString := range queued {
+			if strings.HasPrefix(jobString, jobIdStr+"_") {
+				err = redisClient.LRem(queue, 1, jobString).Err()
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	delayed, err := redisClient.ZRange("gifjob_delayed", 0, -1).Result()
+	if err != nil {
+		return err
+	}
+	for _, jobString := range delayed {
+		if strings.HasPrefix(jobString, jobIdStr+"_") {
+			err = redisClient.ZRem("gifjob_delayed", jobString).Err()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// runCancelListener cancels in-flight renders of jobs as they are cancelled.
+func runCancelListener() {
+	for {
+		pubsub, err := redisClient.Subscribe(cancelChannel)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error subscribing to %s: %v\n", cancelChannel, err)
+			time.Sleep(time.Second)
+			continue
+		}
+		for {
+			msg, err := pubsub.ReceiveMessage()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error receiving from %s: %v\n", cancelChannel, err)
+				break
+			}
+			cancelRenders(msg.Payload)
+		}
+		pubsub.Close()
+		time.Sleep(time.Second)
+	}
+}
@@ -0,0 +1,138 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	pb "github.com/GoogleCloudPlatform/gifinator/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func TestCancelJob(t *testing.T) {
+	tests := []struct {
+		desc   string
+		status pb.GetJobResponse_Status
+		owner  string
+		token  string
+		want   pb.GetJobResponse_Status
+		code   codes.Code
+	}{
+		{"pending", pb.GetJobResponse_PENDING, "secret", "secret", pb.GetJobResponse_CANCELLED, codes.OK},
+		{"already cancelled", pb.GetJobResponse_CANCELLED, "secret", "secret", pb.GetJobResponse_CANCELLED, codes.OK},
+		{"done", pb.GetJobResponse_DONE, "secret", "secret", pb.GetJobResponse_DONE, codes.FailedPrecondition},
+		{"failed", pb.GetJobResponse_FAILED, "secret", "secret", pb.GetJobResponse_FAILED, codes.FailedPrecondition},
+		{"wrong token", pb.GetJobResponse_PENDING, "secret", "guess", pb.GetJobResponse_PENDING, codes.PermissionDenied},
+		{"no token", pb.GetJobResponse_PENDING, "secret", "", pb.GetJobResponse_PENDING, codes.PermissionDenied},
+		{"no owner", pb.GetJobResponse_PENDING, "", "", pb.GetJobResponse_PENDING, codes.PermissionDenied},
+	}
+	for _, tt := range tests {
+		mr, cleanup := newTestRedis(t)
+		job := renderJob{Status: tt.status}
+		if tt.owner != "" {
+			job.OwnerTokenHash = hashOwnerToken(tt.owner)
+		}
+		payload, _ := json.Marshal(job)
+		mr.Set("job_gifjob_1", string(payload))
+		mr.Push("gifjob_queued", "1_0", "2_0", "1_1", "11_0")
+		mr.ZAdd("gifjob_delayed", 1, "1_2")
+		mr.ZAdd("gifjob_delayed", 1, "2_1")
+
+		_, err := server{}.CancelJob(context.Background(), &pb.CancelJobRequest{JobId: "1", OwnerToken: tt.token})
+		if code := grpc.Code(err); code != tt.code {
+			t.Errorf("%s: CancelJob = %v, want %v", tt.desc, err, tt.code)
+		}
+		job, err = loadJob("1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Status != tt.want {
+			t.Errorf("%s: job is %v, want %v", tt.desc, job.Status, tt.want)
+		}
+		queued, _ := mr.List("gifjob_queued")
+		delayed, _ := mr.ZMembers("gifjob_delayed")
+		wantQueued, wantDelayed := "1_0,2_0,1_1,11_0", "1_2,2_1"
+		if tt.status == pb.GetJobResponse_PENDING && tt.code == codes.OK {
+			wantQueued, wantDelayed = "2_0,11_0", "2_1"
+		}
+		if strings.Join(queued, ",") != wantQueued || strings.Join(delayed, ",") != wantDelayed {
+			t.Errorf("%s: queued %v and delayed %v, want %s and %s", tt.desc, queued, delayed, wantQueued, wantDelayed)
+		}
+		cleanup()
+	}
+
+	_, cleanup := newTestRedis(t)
+	defer cleanup()
+	_, err := server{}.CancelJob(context.Background(), &pb.CancelJobRequest{JobId: "2", OwnerToken: "secret"})
+	if code := grpc.Code(err); code != codes.NotFound {
+		t.Errorf("CancelJob of a missing job = %v, want %v", err, codes.NotFound)
+	}
+}
+
+func TestCancelJobPublishes(t *testing.T) {
+	mr, cleanup := newTestRedis(t)
+	defer cleanup()
+	payload, _ := json.Marshal(renderJob{Status: pb.GetJobResponse_PENDING, OwnerTokenHash: hashOwnerToken("secret")})
+	mr.Set("job_gifjob_1", string(payload))
+	pubsub, err := redisClient.Subscribe(cancelChannel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pubsub.Close()
+
+	if _, err := (server{}).CancelJob(context.Background(), &pb.CancelJobRequest{JobId: "1", OwnerToken: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := pubsub.ReceiveMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Payload != "1" {
+		t.Errorf("published %q on %s, want %q", msg.Payload, cancelChannel, "1")
+	}
+}
+
+func TestCancelRenders(t *testing.T) {
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	ctx3, cancel3 := context.WithCancel(context.Background())
+	defer cancel3()
+	untrack1 := trackRender("1", cancel1)
+	untrack2 := trackRender("1", cancel2)
+	untrack3 := trackRender("2", cancel3)
+
+	untrack2()
+	cancelRenders("1")
+	if ctx1.Err() == nil {
+		t.Errorf("render of job 1 not cancelled")
+	}
+	if ctx2.Err() != nil {
+		t.Errorf("untracked render of job 1 cancelled")
+	}
+	if ctx3.Err() != nil {
+		t.Errorf("render of job 2 cancelled")
+	}
+	untrack1()
+	untrack3()
+	if len(inFlight.renders) != 0 {
+		t.Errorf("renders still tracked after untracking: %v", inFlight.renders)
+	}
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	pb "github.com/GoogleCloudPlatform/gifinator/proto"
+	"golang.org/x/net/context"
+)
+
+/**
+ * The scenes that jobs can be started with are listed in the catalog
+ * directory under SCENE_PATH, one JSON manifest per scene. The name of the
+ * manifest, minus ".json", is the scene ID. The files that a manifest names
+ * live in SCENE_PATH itself. Adding a mascot means dropping its templates and
+ * assets into SCENE_PATH and writing a manifest for it.
+ */
+
+// sceneManifest describes a scene in the catalog.
+type sceneManifest struct {
+	Id string `json:"-"`
+
+	// DisplayName is shown to users.
+	DisplayName string `json:"display_name"`
+
+	// ObjTemplate and MtlTemplate are the mesh and its materials. They are
+	// templates that get the job ID, so that the mesh can refer to the job's
+	// materials and the materials to the job's badge.
+	ObjTemplate string `json:"obj_template"`
+	MtlTemplate string `json:"mtl_template"`
+
+	// Assets are the files the materials refer to, such as textures. They
+	// are copied into the bucket under the same names.
+	Assets []string `json:"assets"`
+
+	// Scene is the scene description to render the mesh in. Defaults to
+	// default.scene.json.
+	Scene string `json:"scene"`
+}
+
+// sceneCatalog holds the scenes loaded from SCENE_PATH, by ID.
+var sceneCatalog map[string]*sceneManifest
+
+// legacySceneIds maps the old Product enum onto catalog scenes.
+var legacySceneIds = map[pb.Product]string{
+	pb.Product_UNKNOWN_PRODUCT: "gopher",
+	pb.Product_GO:              "gopher",
+	pb.Product_GRPC:            "grpc",
+	pb.Product_KUBERNETES:      "k8s",
+}
+
+// loadSceneCatalog reads every manifest in dir/catalog.
+func loadSceneCatalog(dir string) (map[string]*sceneManifest, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "catalog", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	catalog := make(map[string]*sceneManifest)
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		var m sceneManifest
+		err = json.NewDecoder(f).Decode(&m)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("scene manifest %s: %v", path, err)
+		}
+		m.Id = strings.TrimSuffix(filepath.Base(path), ".json")
+		if m.DisplayName == "" || m.ObjTemplate == "" || m.MtlTemplate == "" {
+			return nil, fmt.Errorf("scene manifest %s: display_name, obj_template and mtl_template are required", path)
+		}
+		if m.Scene == "" {
+			m.Scene = "default.scene.json"
+		}
+		err = checkSceneAssets(dir, &m)
+		if err != nil {
+			return nil, err
+		}
+		catalog[m.Id] = &m
+	}
+	if len(catalog) == 0 {
+		return nil, fmt.Errorf("no scene manifests in %s", filepath.Join(dir, "catalog"))
+	}
+	return catalog, nil
+}
+
+// sceneIdFor returns the ID of the scene a job asked for.
+func sceneIdFor(req *pb.StartJobRequest) string {
+	if req.SceneId != "" {
+		return req.SceneId
+	}
+	return legacySceneIds[req.ProductToPlug]
+}
+
+func (server) ListScenes(ctx context.Context, req *pb.ListScenesRequest) (*pb.ListScenesResponse, error) {
+	response := &pb.ListScenesResponse{}
+	for _, m := range sceneCatalog {
+		response.Scenes = append(response.Scenes, &pb.Scene{
+			Id:          m.Id,
+			DisplayName: m.DisplayName,
+		})
+	}
+	sort.Sort(byDisplayName(response.Scenes))
+	return response, nil
+}
+
+type byDisplayName []*pb.Scene
+
+func (s byDisplayName) Len() int           { return len(s) }
+func (s byDisplayName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byDisplayName) Less(i, j int) bool { return s[i].DisplayName < s[j].DisplayName }
@@ -0,0 +1,151 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pb "github.com/GoogleCloudPlatform/gifinator/proto"
+	"golang.org/x/net/context"
+)
+
+func TestShippedSceneCatalog(t *testing.T) {
+	catalog, err := loadSceneCatalog("scene")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for product, id := range legacySceneIds {
+		if catalog[id] == nil {
+			t.Errorf("%v jobs use scene %q, which is not in the catalog", product, id)
+		}
+	}
+	for id, m := range catalog {
+		if _, err := os.Stat(filepath.Join("scene", m.Scene)); err != nil {
+			t.Errorf("scene %q: %v", id, err)
+		}
+	}
+}
+
+func TestLoadSceneCatalog(t *testing.T) {
+	const (
+		aMtl = "newmtl a\nmap_Kd a.png\n"
+		bMtl = "newmtl b\nKd 1 1 1\n"
+	)
+	tests := []struct {
+		desc  string
+		files map[string]string
+		want  map[string]sceneManifest
+	}{
+		{"one scene",
+			map[string]string{
+				"catalog/a.json": `{"display_name": "A", "obj_template": "a.obj.tmpl", "mtl_template": "a.mtl.tmpl", "assets": ["a.png"]}`,
+				"a.mtl.tmpl":     aMtl,
+			},
+			map[string]sceneManifest{"a": {Id: "a", DisplayName: "A", ObjTemplate: "a.obj.tmpl", MtlTemplate: "a.mtl.tmpl", Assets: []string{"a.png"}, Scene: "default.scene.json"}}},
+		{"own scene",
+			map[string]string{
+				"catalog/b.json": `{"display_name": "B", "obj_template": "b.obj.tmpl", "mtl_template": "b.mtl.tmpl", "scene": "b.scene.json"}`,
+				"b.mtl.tmpl":     bMtl,
+			},
+			map[string]sceneManifest{"b": {Id: "b", DisplayName: "B", ObjTemplate: "b.obj.tmpl", MtlTemplate: "b.mtl.tmpl", Scene: "b.scene.json"}}},
+		{"not a manifest",
+			map[string]string{
+				"catalog/b.json": `{"display_name": "B", "obj_template": "b.obj.tmpl", "mtl_template": "b.mtl.tmpl"}`,
+				"catalog/README": "hello",
+				"b.mtl.tmpl":     bMtl,
+			},
+			map[string]sceneManifest{"b": {Id: "b", DisplayName: "B", ObjTemplate: "b.obj.tmpl", MtlTemplate: "b.mtl.tmpl", Scene: "default.scene.json"}}},
+		{"no manifests", map[string]string{}, nil},
+		{"bad JSON", map[string]string{"catalog/a.json": `{`}, nil},
+		{"no display name", map[string]string{"catalog/b.json": `{"obj_template": "b.obj.tmpl", "mtl_template": "b.mtl.tmpl"}`, "b.mtl.tmpl": bMtl}, nil},
+		{"no templates", map[string]string{"catalog/a.json": `{"display_name": "A"}`}, nil},
+		{"missing material template", map[string]string{"catalog/b.json": `{"display_name": "B", "obj_template": "b.obj.tmpl", "mtl_template": "b.mtl.tmpl"}`}, nil},
+		{"undeclared texture",
+			map[string]string{
+				"catalog/a.json": `{"display_name": "A", "obj_template": "a.obj.tmpl", "mtl_template": "a.mtl.tmpl"}`,
+				"a.mtl.tmpl":     aMtl,
+			}, nil},
+	}
+	for _, tt := range tests {
+		dir, err := ioutil.TempDir("", "catalog")
+		if err != nil {
+			t.Fatal(err)
+		}
+		os.Mkdir(filepath.Join(dir, "catalog"), 0777)
+		for name, contents := range tt.files {
+			ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0666)
+		}
+		catalog, err := loadSceneCatalog(dir)
+		os.RemoveAll(dir)
+		if (err == nil) != (tt.want != nil) {
+			t.Errorf("%s: loadSceneCatalog = %v, want ok %v", tt.desc, err, tt.want != nil)
+			continue
+		}
+		if len(catalog) != len(tt.want) {
+			t.Errorf("%s: loaded %d scenes, want %d", tt.desc, len(catalog), len(tt.want))
+		}
+		for id, want := range tt.want {
+			got := catalog[id]
+			if got == nil || got.Id != want.Id || got.DisplayName != want.DisplayName || got.ObjTemplate != want.ObjTemplate ||
+				got.MtlTemplate != want.MtlTemplate || got.Scene != want.Scene || len(got.Assets) != len(want.Assets) {
+				t.Errorf("%s: scene %q = %+v, want %+v", tt.desc, id, got, want)
+			}
+		}
+	}
+}
+
+func TestSceneIdFor(t *testing.T) {
+	tests := []struct {
+		req  pb.StartJobRequest
+		want string
+	}{
+		{pb.StartJobRequest{}, "gopher"},
+		{pb.StartJobRequest{ProductToPlug: pb.Product_GO}, "gopher"},
+		{pb.StartJobRequest{ProductToPlug: pb.Product_GRPC}, "grpc"},
+		{pb.StartJobRequest{ProductToPlug: pb.Product_KUBERNETES}, "k8s"},
+		{pb.StartJobRequest{SceneId: "robot"}, "robot"},
+		{pb.StartJobRequest{SceneId: "robot", ProductToPlug: pb.Product_GRPC}, "robot"},
+	}
+	for _, tt := range tests {
+		if got := sceneIdFor(&tt.req); got != tt.want {
+			t.Errorf("sceneIdFor(%v) = %q, want %q", tt.req, got, tt.want)
+		}
+	}
+}
+
+func TestListScenes(t *testing.T) {
+	defer func(c map[string]*sceneManifest) { sceneCatalog = c }(sceneCatalog)
+	sceneCatalog = map[string]*sceneManifest{
+		"k8s":    {Id: "k8s", DisplayName: "Kubernetes"},
+		"gopher": {Id: "gopher", DisplayName: "Go"},
+		"grpc":   {Id: "grpc", DisplayName: "gRPC"},
+	}
+	response, err := server{}.ListScenes(context.Background(), &pb.ListScenesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, s := range response.Scenes {
+		got = append(got, s.Id)
+	}
+	if want := []string{"gopher", "k8s", "grpc"}; len(got) != 3 || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Errorf("ListScenes = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,116 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+	"gopkg.in/redis.v5"
+)
+
+/**
+ * A job started with preview set also gets a draft task for each frame,
+ * rendered at draftSettings, which takes a fraction of a second. Draft tasks
+ * go on gifjob_draft_queued, which workers always empty before taking
+ * anything from gifjob_queued, so a draft is never stuck behind other jobs'
+ * full-quality frames. Their task IDs start with "d" and are counted
+ * separately, so that they never count towards the job's completion.
+ *
+ * Draft frames are written where previews.go expects previews, and so end up
+ * in the job's preview GIF. Their job's full-quality tasks skip progressive
+ * previews, which would overwrite them at a different size. A draft that
+ * fails is dropped rather than retried, since the full-quality frames are on
+ * their way anyway.
+ */
+
+const draftQueue = "gifjob_draft_queued"
+
+// draftPollInterval is how long a worker waits on gifjob_queued before
+// checking for drafts again.
+var draftPollInterval = time.Second
+
+// isDraft reports whether a "<job>_<task>" string is a draft task.
+func isDraft(jobString string) bool {
+	strs := strings.Split(jobString, "_")
+	return len(strs) > 1 && strings.HasPrefix(strs[1], "d")
+}
+
+// queueFor returns the queue that a "<job>_<task>" string belongs on.
+func queueFor(jobString string) string {
+	if isDraft(jobString) {
+		return draftQueue
+	}
+	return "gifjob_queued"
+}
+
+// queueDraft adds a draft task to gifjob_draft_queued.
+func queueDraft(jobIdStr string, task renderTask) error {
+	draftId, err := redisClient.Incr("counter_drafts_gifjob_" + jobIdStr).Result()
+	if err != nil {
+		return err
+	}
+	taskIdStr := "d" + strconv.FormatInt(draftId, 10)
+	payload, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	err = redisClient.Set("task_gifjob_"+jobIdStr+"_"+taskIdStr, payload, 0).Err()
+	if err != nil {
+		return err
+	}
+	err = redisClient.LPush(draftQueue, jobIdStr+"_"+taskIdStr).Err()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "enqueued gifjob_%s_%s %s\n", jobIdStr, taskIdStr, payload)
+	return nil
+}
+
+// leaseTask moves the next task onto gifjob_processing and returns it,
+// taking drafts ahead of everything else.
+func leaseTask() (string, error) {
+	for {
+		jobString, err := redisClient.RPopLPush(draftQueue, "gifjob_processing").Result()
+		if err != redis.Nil {
+			return jobString, err
+		}
+		jobString, err = redisClient.BRPopLPush("gifjob_queued", "gifjob_processing", draftPollInterval).Result()
+		if err != redis.Nil {
+			return jobString, err
+		}
+	}
+}
+
+// completeDraft records that a draft frame has been rendered.
+func completeDraft(ctx context.Context, jobIdStr string, jobString string, task renderTask) error {
+	err := redisClient.LRem("gifjob_processing", 1, jobString).Err()
+	if err != nil {
+		return err
+	}
+	err = releaseLease(jobString)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "deleted gifjob_%s\n", jobString)
+	return previewDone(ctx, jobIdStr, task.Frame)
+}
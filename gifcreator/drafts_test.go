@@ -0,0 +1,127 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsDraft(t *testing.T) {
+	tests := []struct {
+		jobString string
+		want      bool
+	}{
+		{"1_d1", true},
+		{"12_d30", true},
+		{"1_0", false},
+		{"d1_0", false},
+		{"1", false},
+	}
+	for _, tt := range tests {
+		if got := isDraft(tt.jobString); got != tt.want {
+			t.Errorf("isDraft(%q) = %v, want %v", tt.jobString, got, tt.want)
+		}
+	}
+}
+
+func TestQueueDraft(t *testing.T) {
+	mr, cleanup := newTestRedis(t)
+	defer cleanup()
+	for frame := int64(0); frame < 3; frame++ {
+		if err := queueDraft("1", renderTask{Frame: frame}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	queued, _ := mr.List(draftQueue)
+	if strings.Join(queued, ",") != "1_d3,1_d2,1_d1" {
+		t.Errorf("%s holds %v, want [1_d3 1_d2 1_d1]", draftQueue, queued)
+	}
+	for i, jobString := range []string{"1_d1", "1_d2", "1_d3"} {
+		task, err := loadTask(jobString)
+		if err != nil {
+			t.Fatalf("loadTask(%s) = %v", jobString, err)
+		}
+		if task.Frame != int64(i) {
+			t.Errorf("%s renders frame %d, want %d", jobString, task.Frame, i)
+		}
+	}
+	if counter, _ := mr.Get("counter_queued_gifjob_1"); counter != "" {
+		t.Errorf("drafts counted towards the job: counter_queued_gifjob_1 = %s", counter)
+	}
+}
+
+func TestLeaseTask(t *testing.T) {
+	tests := []struct {
+		desc   string
+		drafts []string
+		queued []string
+		want   string
+	}{
+		{"draft ahead of queued", []string{"2_d1"}, []string{"1_0"}, "2_d1,1_0"},
+		{"oldest first", []string{"2_d2", "2_d1"}, []string{"1_1", "1_0"}, "2_d1,2_d2,1_0,1_1"},
+		{"only queued", nil, []string{"1_1", "1_0"}, "1_0,1_1"},
+		{"only drafts", []string{"2_d1"}, nil, "2_d1"},
+	}
+	for _, tt := range tests {
+		mr, cleanup := newTestRedis(t)
+		if len(tt.drafts) > 0 {
+			mr.Push(draftQueue, tt.drafts...)
+		}
+		if len(tt.queued) > 0 {
+			mr.Push("gifjob_queued", tt.queued...)
+		}
+		var leased []string
+		for range append(tt.drafts, tt.queued...) {
+			jobString, err := leaseTask()
+			if err != nil {
+				t.Fatalf("%s: leaseTask = %v", tt.desc, err)
+			}
+			leased = append(leased, jobString)
+		}
+		if strings.Join(leased, ",") != tt.want {
+			t.Errorf("%s: leased %v, want %s", tt.desc, leased, tt.want)
+		}
+		if processing, _ := mr.List("gifjob_processing"); len(processing) != len(leased) {
+			t.Errorf("%s: processing %v, want %v", tt.desc, processing, leased)
+		}
+		cleanup()
+	}
+}
+
+func TestLeaseTaskWaitsForDrafts(t *testing.T) {
+	// Redis blocks for whole seconds at the least
+	defer func(d time.Duration) { draftPollInterval = d }(draftPollInterval)
+	draftPollInterval = time.Second
+	mr, cleanup := newTestRedis(t)
+	defer cleanup()
+
+	// A draft queued while a worker waits on an empty gifjob_queued is
+	// picked up at the next poll
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		payload, _ := json.Marshal(renderTask{})
+		mr.Set("task_gifjob_1_d1", string(payload))
+		mr.Push(draftQueue, "1_d1")
+	}()
+	jobString, err := leaseTask()
+	if err != nil || jobString != "1_d1" {
+		t.Errorf("leaseTask = %q, %v, want 1_d1", jobString, err)
+	}
+}
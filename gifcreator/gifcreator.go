@@ -13,39 +13,43 @@
  * See the License for the specific language governing permissions and
  * limitations under the License.
  */
- 
+
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"image"
-	"image/gif"
+	"image/color/palette"
+	"image/draw"
 	"image/png"
+	"io"
+	"io/ioutil"
 	"log"
+	"math/rand"
 	"net"
 	"os"
 	"strconv"
 	"strings"
-	"time"
-	"bytes"
 	"text/template"
+	"time"
 
 	"gopkg.in/redis.v5"
 
-	pb "github.com/GoogleCloudPlatform/k8s-render-demo/proto"
-	"github.com/GoogleCloudPlatform/k8s-render-demo/internal/gcsref"
+	"github.com/GoogleCloudPlatform/gifinator/internal/blobstore"
+	"github.com/GoogleCloudPlatform/gifinator/internal/gcsref"
+	"github.com/GoogleCloudPlatform/gifinator/internal/gifstream"
+	pb "github.com/GoogleCloudPlatform/gifinator/proto"
+	"github.com/golang/freetype"
+	"golang.org/x/image/font/gofont/gobold"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
-  "golang.org/x/image/font/gofont/gobold"
-	"github.com/golang/freetype"
-	"github.com/bradfitz/slice"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 
-	"cloud.google.com/go/storage"
 	"cloud.google.com/go/trace"
-
-	"google.golang.org/api/iterator"
 )
 
 const serviceName = "gifcreator"
@@ -53,24 +57,48 @@ const serviceName = "gifcreator"
 type server struct{}
 
 type renderJob struct {
+	Seq            int64
+	OwnerTokenHash string
 	Status         pb.GetJobResponse_Status
 	FinalImagePath string
+	Error          *pb.JobError
+	ProductType    pb.Product
+	SceneId        string
+	Caption        string
+	Stage          pb.JobProgress_Stage
+	FramesTotal    int64
+	TilesPerFrame  int
+	FrameDelay     int
+	StartTime      int64
+	UpdateTime     int64
+	EndTime        int64
 }
 
 type renderTask struct {
 	Frame       int64
+	Tile        int
+	TileSize    int32
+	Rotation    float32
+	Quality     pb.Quality
+	Draft       bool
+	HasDraft    bool
+	ScenePath   string
+	Textures    []string
 	Caption     string
 	ProductType pb.Product
+	Attempts    int
 }
 
 var (
 	redisClient   *redis.Client
 	renderClient  pb.RenderClient
-	scenePath			string
-	deploymentId	string
+	scenePath     string
+	deploymentId  string
 	workerMode    = flag.Bool("worker", false, "run in worker mode rather than server")
 	traceClient   *trace.Client
 	gcsBucketName string
+	blobConfig    blobstore.Config
+	blobStore     blobstore.BlobStore
 )
 
 func transform(inputPath string, jobId string) (bytes.Buffer, error) {
@@ -79,40 +107,42 @@ func transform(inputPath string, jobId string) (bytes.Buffer, error) {
 	if err != nil {
 		return transformed, err
 	}
-  err = tmpl.Execute(&transformed, jobId)
+	err = tmpl.Execute(&transformed, jobId)
 	if err != nil {
 		return transformed, err
 	}
 	return transformed, nil
 }
 
-func upload(outBytes []byte, outputPath string, mimeType string, client *storage.Client, ctx context.Context) error {
-	obj, _ := gcsref.Parse(outputPath)
-	wc := client.Bucket(string(obj.Bucket)).Object(obj.Name).NewWriter(ctx)
-	wc.ObjectAttrs.ContentType = mimeType
-	defer wc.Close()
-	if _, err := wc.Write(outBytes); err != nil {
+// blobPath returns the URI of the named object in the configured bucket.
+func blobPath(name string) string {
+	return string(blobConfig.Scheme()) + "://" + gcsBucketName + "/" + name
+}
+
+func upload(outBytes []byte, outputPath string, mimeType string, ctx context.Context) error {
+	ref, err := gcsref.ParseRef(outputPath)
+	if err != nil {
 		return err
 	}
-	return nil
+	return blobstore.Upload(ctx, blobStore, ref, bytes.NewReader(outBytes), mimeType)
 }
 
 func addLabel(img *image.NRGBA, x, y int, label string) error {
-	  fontSize := float64(120)
-		f, err := freetype.ParseFont(gobold.TTF)
-		if err != nil {
-			return err
-		}
-		c := freetype.NewContext()
-		c.SetDPI(float64(72))
-		c.SetFont(f)
-		c.SetFontSize(fontSize)
-		c.SetClip(img.Bounds())
-		c.SetDst(img)
-		c.SetSrc(img)
-		pt := freetype.Pt(x, y+int(c.PointToFixed(fontSize)>>6))
-		_, err = c.DrawString(label, pt)
+	fontSize := float64(120)
+	f, err := freetype.ParseFont(gobold.TTF)
+	if err != nil {
 		return err
+	}
+	c := freetype.NewContext()
+	c.SetDPI(float64(72))
+	c.SetFont(f)
+	c.SetFontSize(fontSize)
+	c.SetClip(img.Bounds())
+	c.SetDst(img)
+	c.SetSrc(img)
+	pt := freetype.Pt(x, y+int(c.PointToFixed(fontSize)>>6))
+	_, err = c.DrawString(label, pt)
+	return err
 }
 
 func (server) StartJob(ctx context.Context, req *pb.StartJobRequest) (*pb.StartJobResponse, error) {
@@ -121,82 +151,172 @@ func (server) StartJob(ctx context.Context, req *pb.StartJobRequest) (*pb.StartJ
 	span.SetLabel("version", deploymentId)
 	defer span.Finish()
 
-	// Retrieive the next job ID from Redis
-	jobId, err := redisClient.Incr("gifjob_counter").Result()
+	anim, err := animationFor(req)
+	if err != nil {
+		return nil, err
+	}
+	quality, err := qualityFor(req)
 	if err != nil {
 		return nil, err
 	}
-	jobIdStr := strconv.FormatInt(jobId, 10)
+	sceneId := sceneIdFor(req)
+	scene, ok := sceneCatalog[sceneId]
+	if !ok {
+		return nil, grpc.Errorf(codes.InvalidArgument, "unknown scene %q", sceneId)
+	}
 
-	// Create a new RenderJob queue for that job
-	var job = renderJob{
-		Status: pb.GetJobResponse_PENDING,
+	// Pick a random job ID, and take the next sequence number from Redis to
+	// order the job in the index
+	jobIdStr, err := newJobId()
+	if err != nil {
+		return nil, err
 	}
-	payload, _ := json.Marshal(job)
-	err = redisClient.Set("job_gifjob_"+jobIdStr, payload, 0).Err()
+	ownerToken, err := newOwnerToken()
 	if err != nil {
 		return nil, err
 	}
-
-  gcsClient, err := storage.NewClient(ctx)
-
-  var productString string
-	switch(req.ProductToPlug){
-	case pb.Product_GRPC:
-		productString = "grpc"
-		break
-	case pb.Product_KUBERNETES:
-		productString = "k8s"
-		break
-	default:
-		productString = "gopher"
+	seq, err := redisClient.Incr("gifjob_counter").Result()
+	if err != nil {
+		return nil, err
 	}
+	response := &pb.StartJobResponse{JobId: jobIdStr, OwnerToken: ownerToken}
 
-	// Generate the assets needed to render the frame, and push them to GCS
-	t, err := transform(scenePath+"/"+productString+".obj.tmpl", jobIdStr)
+	// Create a new RenderJob queue for that job
+	var job = renderJob{
+		Seq:            seq,
+		OwnerTokenHash: hashOwnerToken(ownerToken),
+		Status:         pb.GetJobResponse_PENDING,
+		ProductType:    req.ProductToPlug,
+		SceneId:        sceneId,
+		Caption:        req.Name,
+		Stage:          pb.JobProgress_PREPARING_ASSETS,
+		StartTime:      time.Now().Unix(),
+	}
+	err = createJob(jobIdStr, job)
 	if err != nil {
 		return nil, err
 	}
+
+	// Generate the assets needed to render the frame, and push them to GCS.
+	// If any of this fails the job is marked as failed, and the caller still
+	// gets its ID so that it can show the user what went wrong.
+	t, err := transform(scenePath+"/"+scene.ObjTemplate, jobIdStr)
+	if err != nil {
+		return failStartJob(response, pb.JobError_TRANSFORM_TEMPLATES, err)
+	}
 	err = upload(t.Bytes(),
-		"gs://" + gcsBucketName + "/job_"+jobIdStr+".obj",
-		"binary/octet-stream", gcsClient, ctx)
+		blobPath("job_"+jobIdStr+".obj"),
+		"binary/octet-stream", ctx)
 	if err != nil {
-		return nil, err
+		return failStartJob(response, pb.JobError_UPLOAD_ASSETS, err)
 	}
-	t, err = transform(scenePath+"/"+productString+".mtl.tmpl", jobIdStr)
+	t, err = transform(scenePath+"/"+scene.MtlTemplate, jobIdStr)
 	if err != nil {
-		return nil, err
+		return failStartJob(response, pb.JobError_TRANSFORM_TEMPLATES, err)
+	}
+	textures, err := mtlTextures(bytes.NewReader(t.Bytes()))
+	if err != nil {
+		return failStartJob(response, pb.JobError_TRANSFORM_TEMPLATES, err)
 	}
 	err = upload(t.Bytes(),
-		"gs://" + gcsBucketName + "/job_"+jobIdStr+".mtl",
-		"binary/octet-stream", gcsClient, ctx)
+		blobPath("job_"+jobIdStr+".mtl"),
+		"binary/octet-stream", ctx)
 	if err != nil {
-		return nil, err
+		return failStartJob(response, pb.JobError_UPLOAD_ASSETS, err)
 	}
-	badgeFile, err := os.Open(scenePath+"/gcp_next_badge.png")
+	badgeFile, err := os.Open(scenePath + "/gcp_next_badge.png")
 	if err != nil {
-		return nil, err
+		return failStartJob(response, pb.JobError_UPLOAD_BADGE, err)
 	}
+	defer badgeFile.Close()
 	badgeImg, err := png.Decode(badgeFile)
 	if err != nil {
-		return nil, err
+		return failStartJob(response, pb.JobError_UPLOAD_BADGE, err)
+	}
+	err = addLabel(badgeImg.(*image.NRGBA), 90, 120, req.Name)
+	if err != nil {
+		return failStartJob(response, pb.JobError_UPLOAD_BADGE, err)
 	}
-	addLabel(badgeImg.(*image.NRGBA), 90, 120, req.Name)
 	buf := new(bytes.Buffer)
-  err = png.Encode(buf, badgeImg)
+	err = png.Encode(buf, badgeImg)
+	if err != nil {
+		return failStartJob(response, pb.JobError_UPLOAD_BADGE, err)
+	}
 	err = upload(buf.Bytes(),
-		"gs://" + gcsBucketName + "/job_"+jobIdStr+"_badge.png",
-	  "image/png", gcsClient, ctx)
+		blobPath("job_"+jobIdStr+"_badge.png"),
+		"image/png", ctx)
+	if err != nil {
+		return failStartJob(response, pb.JobError_UPLOAD_BADGE, err)
+	}
+
+	// Ship the scene description along with the mesh. Without one, the
+	// render service falls back to its default scene
+	var jobScenePath string
+	sceneBytes, err := ioutil.ReadFile(scenePath + "/" + scene.Scene)
+	if err == nil {
+		jobScenePath = blobPath("job_" + jobIdStr + ".scene.json")
+		err = upload(sceneBytes, jobScenePath, "application/json", ctx)
+	} else if os.IsNotExist(err) {
+		err = nil
+	}
+	if err != nil {
+		return failStartJob(response, pb.JobError_UPLOAD_ASSETS, err)
+	}
+
+	// Move the job on to rendering before any worker can pick up its frames
+	settings := settingsFor(quality)
+	tiles := 1
+	var jobTileSize int32
+	if tileSize > 0 && (settings.Width > tileSize || settings.Height > tileSize) {
+		// Large frames are split into tiles that can render in parallel
+		jobTileSize = tileSize
+		tiles = len(tileRegions(settings.Width, settings.Height, tileSize))
+	}
+	_, err = updateJob(jobIdStr, func(job *renderJob) {
+		job.Stage = pb.JobProgress_RENDERING
+		job.FramesTotal = int64(anim.FrameCount)
+		job.FrameDelay = anim.delay()
+		if tiles > 1 {
+			job.TilesPerFrame = tiles
+		}
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// Add tasks to the GifJob queue for each frame to render
+	// Queue the draft first, so that its frames are leased ahead of the
+	// full-quality ones (see drafts.go)
+	if req.Preview {
+		for i := 0; i < anim.FrameCount; i++ {
+			err = queueDraft(jobIdStr, renderTask{
+				Frame:       int64(i),
+				Rotation:    anim.rotation(i),
+				Quality:     quality,
+				Draft:       true,
+				ScenePath:   jobScenePath,
+				Textures:    textures,
+				ProductType: req.ProductToPlug,
+				Caption:     req.Name,
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Add tasks to the GifJob queue for each frame, or tile, to render
 	var taskId int64
-	for i := 0; i < 15; i++ {
+	for i := 0; i < anim.FrameCount*tiles; i++ {
 		// Set up render request for each frame
 		var task = renderTask{
-			Frame:       int64(i),
+			Frame:       int64(i / tiles),
+			Tile:        i % tiles,
+			TileSize:    jobTileSize,
+			Rotation:    anim.rotation(i / tiles),
+			Quality:     quality,
+			HasDraft:    req.Preview,
+			ScenePath:   jobScenePath,
+			Textures:    textures,
 			ProductType: req.ProductToPlug,
 			Caption:     req.Name,
 		}
@@ -208,7 +328,7 @@ func (server) StartJob(ctx context.Context, req *pb.StartJobRequest) (*pb.StartJ
 		}
 		taskIdStr := strconv.FormatInt(taskId, 10)
 
-		payload, err = json.Marshal(task)
+		payload, err := json.Marshal(task)
 		if err != nil {
 			return nil, err
 		}
@@ -224,9 +344,21 @@ func (server) StartJob(ctx context.Context, req *pb.StartJobRequest) (*pb.StartJ
 	}
 
 	// Return job ID
-	response := pb.StartJobResponse{JobId: jobIdStr}
+	return response, nil
+}
 
-	return &response, nil
+// failStartJob marks a job as failed at the given stage of StartJob and
+// returns the job anyway, so that GetJob can report the failure.
+func failStartJob(response *pb.StartJobResponse, stage pb.JobError_Stage, cause error) (*pb.StartJobResponse, error) {
+	fmt.Fprintf(os.Stderr, "job_gifjob_%s failed at %v: %v\n", response.JobId, stage, cause)
+	err := markJobFailed(response.JobId, &pb.JobError{
+		Stage:   stage,
+		Message: cause.Error(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
 }
 
 func leaseNextTask() error {
@@ -234,73 +366,242 @@ func leaseNextTask() error {
 	 * We want to make task leasing as robust as possible. We do this by
 	 * shifting the task marker to a 'processing' queue that signals that we are
 	 * trying to work on it. Once the task is done it's removed from the
-	 * processing queue. If this process crashes during processing then a garbage
-	 * collector could move the task back into the 'queueing' queue.
+	 * processing queue. If this process crashes during processing then the
+	 * reaper moves the task back into the 'queueing' queue once its lease
+	 * expires (see reaper.go).
 	 */
 	span := traceClient.NewSpan("gifCreator.leaseNextTask")
- 	span.SetLabel("service", serviceName)
- 	span.SetLabel("version", deploymentId)
- 	tCtx := trace.NewContext(context.Background(), span)
- 	defer span.Finish()
+	span.SetLabel("service", serviceName)
+	span.SetLabel("version", deploymentId)
+	tCtx := trace.NewContext(context.Background(), span)
+	defer span.Finish()
 
-	jobString, err := redisClient.BRPopLPush("gifjob_queued", "gifjob_processing", 0).Result()
+	jobString, err := leaseTask()
 	if err != nil {
 		return err
 	}
 	fmt.Fprintf(os.Stdout, "leased gifjob_%s\n", jobString)
+	err = recordLease(jobString)
+	if err != nil {
+		return err
+	}
 
 	// extract task ID and job ID
 	strs := strings.Split(jobString, "_")
 	jobIdStr := strs[0]
-	taskIdStr := strs[1]
 
-	payload, err := redisClient.Get("task_gifjob_" + jobIdStr + "_" + taskIdStr).Result()
+	// Take any more frames of the same job that are next in the queue, so
+	// that they can be rendered together (see batch.go). Drafts are cheap
+	// enough to render one at a time
+	jobStrings := []string{jobString}
+	if renderBatchSize > 1 && !isDraft(jobString) {
+		more, err := leaseMoreTasks(jobIdStr, renderBatchSize-1)
+		if err != nil {
+			return err
+		}
+		jobStrings = append(jobStrings, more...)
+	}
+
+	tasks := make([]renderTask, len(jobStrings))
+	for i, jobString := range jobStrings {
+		tasks[i], err = loadTask(jobString)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Register the render before checking on the job, so that a cancellation
+	// either shows up in the check or cancels the render
+	renderCtx, cancelRender := context.WithCancel(tCtx)
+	defer cancelRender()
+	untrackRender := trackRender(jobIdStr, cancelRender)
+	defer untrackRender()
+
+	// Don't waste a render on a job that has already failed or been cancelled
+	stopped, err := jobStopped(jobIdStr)
 	if err != nil {
 		return err
 	}
-	fmt.Fprintf(os.Stdout, "leased gifjob_%s %s\n", jobString, payload)
+	if stopped {
+		for _, jobString := range jobStrings {
+			fmt.Fprintf(os.Stdout, "dropping gifjob_%s, job has stopped\n", jobString)
+			err = redisClient.LRem("gifjob_processing", 1, jobString).Err()
+			if err != nil {
+				return err
+			}
+			err = releaseLease(jobString)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if len(tasks) > 1 {
+		return renderBatch(tCtx, renderCtx, jobIdStr, jobStrings, tasks)
+	}
+
+	req := frameRequestFor(jobIdStr, tasks[0])
+	stopRenewing := renewLease(jobString)
+	var trailer metadata.MD
+	if wantsPreviews(tasks[0]) {
+		err = renderWithPreviews(tCtx, renderCtx, jobIdStr, tasks[0], req, &trailer)
+	} else {
+		_, err =
+			renderClient.RenderFrame(renderCtx, req, grpc.Trailer(&trailer))
+	}
+	stopRenewing()
 
+	if err != nil {
+		return abandonTask(renderCtx, jobString, err, trailer)
+	}
+	if tasks[0].Draft {
+		return completeDraft(tCtx, jobIdStr, jobString, tasks[0])
+	}
+	return completeTask(tCtx, jobIdStr, jobString, tasks[0])
+}
+
+// loadTask reads the task that a "<job>_<task>" string refers to.
+func loadTask(jobString string) (renderTask, error) {
 	var task renderTask
-	err = json.Unmarshal([]byte(payload), &task)
+	strs := strings.Split(jobString, "_")
+	payload, err := redisClient.Get("task_gifjob_" + strs[0] + "_" + strs[1]).Result()
 	if err != nil {
-		return err
+		return task, err
 	}
+	fmt.Fprintf(os.Stdout, "leased gifjob_%s %s\n", jobString, payload)
+	err = json.Unmarshal([]byte(payload), &task)
+	return task, err
+}
+
+// frameRequestFor builds the request to render a task.
+func frameRequestFor(jobIdStr string, task renderTask) *pb.RenderRequest {
+	req := renderRequestFor(jobIdStr, task)
+	req.GcsOutputBase = outputBaseFor(jobIdStr, task)
+	req.Rotation = task.Rotation
+	req.Region = task.region()
+	return req
+}
 
-	outputPrefix := "out." + jobIdStr
-	outputBasePath := "gs://" + gcsBucketName + "/" + outputPrefix
-	req := &pb.RenderRequest{
-		GcsOutputBase: outputBasePath,
-		ObjPath: "gs://" + gcsBucketName + "/job_"+jobIdStr+".obj",
-		Assets: []string{
-			"gs://" + gcsBucketName + "/job_"+jobIdStr+".mtl",
-			"gs://" + gcsBucketName + "/job_"+jobIdStr+"_badge.png",
-			"gs://" + gcsBucketName + "/k8s.png",
-			"gs://" + gcsBucketName + "/grpc.png",
-		},
-		Rotation: float32(task.Frame*2+20),
-		Iterations: 1,
+// renderRequestFor describes the scene a task renders. The output path,
+// rotation and region are left for the caller to fill in.
+func renderRequestFor(jobIdStr string, task renderTask) *pb.RenderRequest {
+	settings := settingsFor(task.Quality)
+	if task.Draft {
+		settings = draftSettings
+	}
+	// The render service needs the materials and the textures they name
+	assets := []string{blobPath("job_" + jobIdStr + ".mtl")}
+	textures := task.Textures
+	if textures == nil {
+		// Tasks queued before textures were worked out per job
+		textures = []string{"job_" + jobIdStr + "_badge.png", "k8s.png", "grpc.png"}
+	}
+	for _, texture := range textures {
+		assets = append(assets, blobPath(texture))
+	}
+	return &pb.RenderRequest{
+		ObjPath:         blobPath("job_" + jobIdStr + ".obj"),
+		Assets:          assets,
+		Iterations:      settings.Iterations,
+		Width:           settings.Width,
+		Height:          settings.Height,
+		SamplesPerPixel: settings.SamplesPerPixel,
+		ScenePath:       task.ScenePath,
 	}
-	_, err =
-		renderClient.RenderFrame(tCtx, req)
+}
 
-	if err != nil {
-		// TODO(jessup) Swap these out for proper logging
-		fmt.Fprintf(os.Stderr, "error requesting frame - %v\n", err)
+// abandonTask gives up on a leased task whose render failed, handing it to
+// the retry policy unless its job was cancelled. It returns the render error.
+func abandonTask(renderCtx context.Context, jobString string, err error, trailer metadata.MD) error {
+	// TODO(jessup) Swap these out for proper logging
+	fmt.Fprintf(os.Stderr, "error requesting frame - %v\n", err)
+	removed, lremErr := redisClient.LRem("gifjob_processing", 1, jobString).Result()
+	if lremErr != nil {
+		return lremErr
+	}
+	if renderCtx.Err() != nil {
+		// The job was cancelled, so the frame isn't wanted any more
+		fmt.Fprintf(os.Stdout, "abandoned gifjob_%s, job was cancelled\n", jobString)
+		if removed == 1 {
+			return releaseLease(jobString)
+		}
+		return nil
+	}
+	if removed == 1 && grpc.Code(err) == codes.ResourceExhausted {
+		// The render service is too busy to take it, which says nothing
+		// about the task, so try again when it says there will be room,
+		// without counting an attempt
+		if deferErr := deferTask(jobString, retryAfter(trailer)); deferErr != nil {
+			return deferErr
+		}
 		return err
 	}
+	if removed == 1 {
+		if failErr := failTask(jobString, err); failErr != nil {
+			return failErr
+		}
+	}
+	return err
+}
 
+// completeTask records that a leased task's frame or tile has been rendered,
+// and compiles the GIF once it was the job's last.
+func completeTask(tCtx context.Context, jobIdStr string, jobString string, task renderTask) error {
 	// delete item from gifjob_processing
-	err = redisClient.LRem("gifjob_processing", 1, jobString).Err()
+	removed, err := redisClient.LRem("gifjob_processing", 1, jobString).Result()
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		// The reaper took us for dead and requeued the task. Take it back off
+		// the queue if nobody has picked it up yet, otherwise leave the
+		// completion to whoever has it now.
+		removed, err = redisClient.ZRem("gifjob_delayed", jobString).Result()
+		if err != nil {
+			return err
+		}
+		if removed == 0 {
+			removed, err = redisClient.LRem("gifjob_queued", 1, jobString).Result()
+			if err != nil {
+				return err
+			}
+		}
+		if removed == 0 {
+			fmt.Fprintf(os.Stdout, "gifjob_%s was reaped and re-leased, not counting it\n", jobString)
+			return nil
+		}
+	}
+	err = releaseLease(jobString)
 	if err != nil {
 		return err
 	}
 	fmt.Fprintf(os.Stdout, "deleted gifjob_%s\n", jobString)
 
+	// Put a tiled frame together once its last tile is in, before the task
+	// counts towards the job, so every frame is whole by the time the GIF is
+	// compiled
+	if task.TileSize > 0 {
+		err = tileDone(tCtx, jobIdStr, task)
+		if err != nil {
+			markErr := markJobFailed(jobIdStr, &pb.JobError{
+				Stage:   pb.JobError_STITCH_TILES,
+				Frame:   task.Frame,
+				Message: err.Error(),
+			})
+			if markErr != nil {
+				return markErr
+			}
+			return err
+		}
+	}
+
 	// increment "gifjob_"+jobIdStr+"_completed_counter"
 	completedTaskCount, err := redisClient.Incr("counter_completed_gifjob_" + jobIdStr).Result()
 	if err != nil {
 		return err
 	}
+	publishJobUpdate(jobIdStr)
 	queueLength, err := redisClient.Get("counter_queued_gifjob_" + jobIdStr).Result()
 	if err != nil {
 		return err
@@ -309,17 +610,42 @@ func leaseNextTask() error {
 	queueLengthInt, _ := strconv.ParseInt(queueLength, 10, 64)
 	fmt.Fprintf(os.Stdout, "job_gifjob_%s : %d of %d tasks done\n", jobIdStr, completedTaskCount, queueLengthInt)
 	if completedTaskCount == queueLengthInt {
-		finalImagePath, err := compileGifs(outputPrefix, tCtx)
+		err = setJobStage(jobIdStr, pb.JobProgress_COMPOSITING)
+		if err == errJobFinished {
+			fmt.Fprintf(os.Stdout, "not compiling job_gifjob_%s, job has stopped\n", jobIdStr)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		finalImagePath, err := compileGifs(jobIdStr, tCtx)
 		if err != nil {
+			stopped, stoppedErr := jobStopped(jobIdStr)
+			if stoppedErr == nil && stopped {
+				// Cancelled or failed while the GIF was being put together,
+				// which stops the upload
+				fmt.Fprintf(os.Stdout, "stopped compiling job_gifjob_%s, job has stopped\n", jobIdStr)
+				return nil
+			}
+			markErr := markJobFailed(jobIdStr, &pb.JobError{
+				Stage:   pb.JobError_COMPILE_GIF,
+				Message: err.Error(),
+			})
+			if markErr != nil {
+				return markErr
+			}
 			return err
 		}
 		fmt.Fprintf(os.Stdout, "final image path: %s\n", finalImagePath)
-		var job = renderJob{
-			Status:         pb.GetJobResponse_DONE,
-			FinalImagePath: finalImagePath,
+		_, err = updateJob(jobIdStr, func(job *renderJob) {
+			job.Status = pb.GetJobResponse_DONE
+			job.FinalImagePath = finalImagePath
+			job.EndTime = time.Now().Unix()
+		})
+		if err == errJobFinished {
+			// Cancelled while the GIF was being put together
+			return nil
 		}
-		payloadBytes, _ := json.Marshal(job)
-		err = redisClient.Set("job_gifjob_"+jobIdStr, payloadBytes, 0).Err()
 		if err != nil {
 			return err
 		}
@@ -330,82 +656,78 @@ func leaseNextTask() error {
 }
 
 /**
- * compileGifs() will glob all GCS objects prefixed with prefix, and
- * stitch them together into an animated GIF, store that in GCS and return the
- * path of the final image
+ * compileGifs() will list all rendered frames of a job, and stitch them
+ * together into an animated GIF, store that in the blob store and return the
+ * public URL of the final image. The GIF is uploaded as it is encoded, and
+ * the job moves on to the UPLOADING stage once the last frame is encoded
  */
-func compileGifs(prefix string, tCtx context.Context) (string, error) {
-	gcsClient, err := storage.NewClient(tCtx)
+func compileGifs(jobIdStr string, tCtx context.Context) (string, error) {
+	job, err := loadJob(jobIdStr)
 	if err != nil {
 		return "", err
 	}
-	it := gcsClient.Bucket(gcsBucketName).Objects(tCtx, &storage.Query{Prefix: prefix, Versions: false})
-	// Results from GCS are unordered, so pull the list into memory and sort it
-  var orderedObjects []storage.ObjectAttrs
-	for {
-		obj, err := it.Next()
-		if err == iterator.Done {
-			break
-		}
-		orderedObjects = append(orderedObjects, *obj)
+	return encodeGif(tCtx, framePrefix(jobIdStr), "out."+jobIdStr+"/animated.gif", job.FrameDelay, func() error {
+		return setJobStage(jobIdStr, pb.JobProgress_UPLOADING)
+	})
+}
+
+// encodeGif stitches the PNG frames under prefix together into an animated
+// GIF, stores it under name and returns its public URL. If encoded is not
+// nil, it is called once the last frame has been encoded.
+func encodeGif(tCtx context.Context, prefix string, name string, delay int, encoded func() error) (string, error) {
+	// The store returns objects ordered by name, which is frame order
+	objects, err := blobStore.List(tCtx, gcsref.MustParseRef(blobPath(prefix)))
+	if err != nil {
+		return "", err
 	}
-	slice.Sort(orderedObjects[:], func(i, j int) bool {
-    return orderedObjects[i].Name < orderedObjects[j].Name
-  })
 
-	finalGif := &gif.GIF{}
-	for _, objAttrs := range orderedObjects {
-		fmt.Fprintf(os.Stdout, "DEBUG bucket %s prefix %s attrs %v\n", gcsBucketName, prefix, objAttrs)
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			return "", err
+	finalObj := gcsref.MustParseRef(blobPath(name))
+	fmt.Fprintf(os.Stdout, "starting writing final: %v\n", finalObj)
+
+	// Encode the frames one at a time straight into the upload, so that
+	// only one frame is ever held in memory
+	pr, pw := io.Pipe()
+	go func() {
+		err := encodeFrames(tCtx, pw, objects, delay)
+		if err == nil && encoded != nil {
+			err = encoded()
 		}
-		rc, err := gcsClient.Bucket(objAttrs.Bucket).Object(objAttrs.Name).NewReader(tCtx)
+		pw.CloseWithError(err)
+	}()
+	err = blobstore.Upload(tCtx, blobStore, finalObj, pr, "image/gif")
+	pr.CloseWithError(err)
+	if err != nil {
+		return "", err
+	}
+
+	// Make the final image public and return its URL
+	return blobStore.Publish(tCtx, finalObj)
+}
+
+// encodeFrames writes the PNG frames as an animated GIF.
+func encodeFrames(ctx context.Context, w io.Writer, frames []blobstore.Attrs, delay int) error {
+	enc := gifstream.NewEncoder(w)
+	for i := range frames {
+		rc, err := blobStore.Get(ctx, frames[i].Ref)
 		if err != nil {
-			return "", err
+			return err
 		}
-		fmt.Fprintf(os.Stdout, "DEBUG decoding bucket %s object %s\n", objAttrs.Bucket, objAttrs.Name)
 		framePng, err := png.Decode(rc)
+		rc.Close()
 		if err != nil {
-			return "", err
+			return err
 		}
 
-    var gifBuf bytes.Buffer
-		var opt gif.Options
-	  opt.NumColors = 256
-		err = gif.Encode(&gifBuf, framePng, &opt)
-
-		frameGif, err := gif.Decode(&gifBuf)
+		// Map the frame onto the same 256 colors that gif.Encode would
+		b := framePng.Bounds()
+		frameGif := image.NewPaletted(b, palette.Plan9)
+		draw.FloydSteinberg.Draw(frameGif, b, framePng, b.Min)
+		err = enc.WriteFrame(frameGif, delay)
 		if err != nil {
-			return "", err
+			return err
 		}
-
-		rc.Close()
-		finalGif.Image = append(finalGif.Image, frameGif.(*image.Paletted))
-		finalGif.Delay = append(finalGif.Delay, 0)
-	}
-
-	finalObjName := prefix + "/animated.gif"
-	finalObj := gcsClient.Bucket(gcsBucketName).Object(finalObjName)
-	wc := finalObj.NewWriter(tCtx)
-
-	wc.ObjectAttrs.ContentType = "image/gif"
-	fmt.Fprintf(os.Stdout, "starting writing final: %s\n", finalObjName)
-	err = gif.EncodeAll(wc, finalGif)
-	if err != nil {
-		return "", err
-	}
-	wc.Close()
-
-	// Make the final image public
-	if err := finalObj.ACL().Set(tCtx, storage.AllUsers, storage.RoleReader); err != nil {
-		return "", err
 	}
-
-	// Return GCS URI to the public image (sans the protcol)
-	return gcsBucketName + ".storage.googleapis.com/" + finalObjName, nil
+	return enc.Close()
 }
 
 func (server) GetJob(ctx context.Context, req *pb.GetJobRequest) (*pb.GetJobResponse, error) {
@@ -414,18 +736,12 @@ func (server) GetJob(ctx context.Context, req *pb.GetJobRequest) (*pb.GetJobResp
 	span.SetLabel("version", deploymentId)
 	defer span.Finish()
 
-	var job renderJob
-	statusStr, err := redisClient.Get("job_gifjob_" + string(req.JobId)).Result()
-	if err != nil {
-		return nil, err
-	}
-	fmt.Fprintf(os.Stdout, "status of gifjob_%s is %s\n", req.JobId, statusStr)
-	err = json.Unmarshal([]byte(statusStr), &job)
+	response, err := jobStatus(req.JobId)
 	if err != nil {
 		return nil, err
 	}
-	response := pb.GetJobResponse{ImageUrl: job.FinalImagePath, Status: job.Status}
-	return &response, nil
+	fmt.Fprintf(os.Stdout, "status of gifjob_%s is %v\n", req.JobId, response)
+	return response, nil
 }
 
 func main() {
@@ -443,9 +759,42 @@ func main() {
 	renderName := os.Getenv("RENDER_NAME")
 	renderPort := os.Getenv("RENDER_PORT")
 	renderHostAddr := renderName + ":" + renderPort
-  deploymentId = os.Getenv("DEPLOYMENT_ID")
+	deploymentId = os.Getenv("DEPLOYMENT_ID")
 	gcsBucketName = os.Getenv("GCS_BUCKET_NAME")
 	scenePath = os.Getenv("SCENE_PATH")
+	sceneCatalog, err = loadSceneCatalog(scenePath)
+	if err != nil {
+		log.Fatalf("cannot load scene catalog: %v", err)
+	}
+	if v := os.Getenv("MAX_FRAME_COUNT"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			maxFrameCount = n
+		} else {
+			fmt.Fprintf(os.Stderr, "ignoring invalid MAX_FRAME_COUNT %q, using %d\n", v, maxFrameCount)
+		}
+	}
+	if v := os.Getenv("MAX_FRAMES_PER_SECOND"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			maxFramesPerSecond = n
+		} else {
+			fmt.Fprintf(os.Stderr, "ignoring invalid MAX_FRAMES_PER_SECOND %q, using %d\n", v, maxFramesPerSecond)
+		}
+	}
+	if q, ok := pb.Quality_value[strings.ToUpper(os.Getenv("MAX_QUALITY"))]; ok && q > 0 {
+		maxQuality = pb.Quality(q)
+	}
+	if n, err := strconv.Atoi(os.Getenv("TILE_SIZE")); err == nil && n >= 0 {
+		tileSize = int32(n)
+	}
+	if token := os.Getenv("OPERATOR_TOKEN"); token != "" {
+		operatorTokenHash = hashOwnerToken(token)
+	}
+	blobConfig = blobstore.ConfigFromEnv()
+
+	blobStore, err = blobstore.Open(context.Background(), blobConfig)
+	if err != nil {
+		log.Fatalf("cannot open blob store: %v", err)
+	}
 
 	redisClient = redis.NewClient(&redis.Options{
 		Addr:     redisName + ":" + redisPort,
@@ -462,17 +811,54 @@ func main() {
 			log.Fatal(err)
 		}
 
-		conn, err := grpc.Dial(renderHostAddr,
-			trace.EnableGRPCTracingDialOption, grpc.WithInsecure())
+		if peersName := os.Getenv("RENDER_PEERS_NAME"); peersName != "" {
+			// Steer each render to the least busy replica (see steer.go)
+			rand.Seed(time.Now().UnixNano())
+			pool, err := newRenderPool(peersName, renderPort,
+				trace.EnableGRPCTracingDialOption, grpc.WithInsecure())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "cannot connect to render replicas %s\n%v", peersName, err)
+				return
+			}
+			go pool.run()
+			renderClient = pool
+		} else {
+			conn, err := grpc.Dial(renderHostAddr,
+				trace.EnableGRPCTracingDialOption, grpc.WithInsecure())
+
+			if err != nil {
+				// TODO(jessup) Swap these out for proper logging
+				fmt.Fprintf(os.Stderr, "cannot connect to render service %s\n%v", renderHostAddr, err)
+				return
+			}
+			defer conn.Close()
 
-		if err != nil {
-			// TODO(jessup) Swap these out for proper logging
-			fmt.Fprintf(os.Stderr, "cannot connect to render service %s\n%v", renderHostAddr, err)
-			return
+			renderClient = pb.NewRenderClient(conn)
 		}
-		defer conn.Close()
 
-		renderClient = pb.NewRenderClient(conn)
+		hostname, _ := os.Hostname()
+		workerId = hostname + "-" + strconv.Itoa(os.Getpid())
+		if d, err := time.ParseDuration(os.Getenv("LEASE_TIMEOUT")); err == nil && d > 0 {
+			leaseTimeout = d
+		}
+		if d, err := time.ParseDuration(os.Getenv("REAP_INTERVAL")); err == nil && d > 0 {
+			reapInterval = d
+		}
+		if n, err := strconv.Atoi(os.Getenv("MAX_TASK_ATTEMPTS")); err == nil && n > 0 {
+			maxTaskAttempts = n
+		}
+		if d, err := time.ParseDuration(os.Getenv("RETRY_BACKOFF")); err == nil && d > 0 {
+			retryBackoff = d
+		}
+		if d, err := time.ParseDuration(os.Getenv("MAX_RETRY_BACKOFF")); err == nil && d > 0 {
+			maxRetryBackoff = d
+		}
+		if n, err := strconv.Atoi(os.Getenv("RENDER_BATCH_SIZE")); err == nil && n > 0 {
+			renderBatchSize = n
+		}
+		go runReaper()
+		go runRetryScheduler()
+		go runCancelListener()
 
 		for {
 			err := leaseNextTask()
@@ -480,11 +866,14 @@ func main() {
 				fmt.Fprintf(os.Stderr, "error working on task: %v\n", err)
 			}
 			time.Sleep(10 * time.Millisecond)
-			// TODO(jessup) add timed sweeps for crashed jobs that never finished processing
 		}
 	} else {
 		// Server mode will act as a gRPC server
 		fmt.Fprintf(os.Stdout, "starting gifcreator in server mode\n")
+		err = syncSceneAssets(context.Background(), scenePath)
+		if err != nil {
+			log.Fatalf("cannot upload scene assets: %v", err)
+		}
 		l, err := net.Listen("tcp", ":"+port)
 		if err != nil {
 			log.Fatalf("listen failed: %v", err)
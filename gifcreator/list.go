@@ -0,0 +1,180 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"strconv"
+
+	pb "github.com/GoogleCloudPlatform/gifinator/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"gopkg.in/redis.v5"
+)
+
+/**
+ * Jobs are indexed in Redis sorted sets scored by their sequence number from
+ * gifjob_counter, so that walking a set backwards lists the newest jobs
+ * first. gifjob_index holds every job,
+ * gifjob_index_status_<STATUS> the jobs currently in each status,
+ * gifjob_index_scene_<SCENE> the jobs for each scene in the catalog, and
+ * gifjob_index_product_<PRODUCT> the jobs for each product. createJob and
+ * updateJob keep the sets up to date, in the same transaction as the job
+ * record, as jobs are started and move between states.
+ *
+ * ListJobs walks the narrowest set that matches its filters, and checks any
+ * remaining filter against the job records. A page token is the score of the
+ * last job on the page.
+ */
+
+const (
+	defaultListPageSize = 20
+	maxListPageSize     = 100
+)
+
+func statusIndexKey(status pb.GetJobResponse_Status) string {
+	return "gifjob_index_status_" + status.String()
+}
+
+func productIndexKey(product pb.Product) string {
+	return "gifjob_index_product_" + product.String()
+}
+
+func sceneIndexKey(sceneId string) string {
+	return "gifjob_index_scene_" + sceneId
+}
+
+// jobSceneId returns the catalog scene a job was rendered in.
+func jobSceneId(job renderJob) string {
+	if job.SceneId == "" {
+		// Jobs from before the scene catalog only have a product
+		return legacySceneIds[job.ProductType]
+	}
+	return job.SceneId
+}
+
+// indexJob queues the commands that record a job in the index sets, under
+// its current status only, on pipe.
+func indexJob(pipe *redis.Pipeline, jobIdStr string, job renderJob) error {
+	score := float64(job.Seq)
+	if job.Seq == 0 {
+		// Jobs from before random IDs use their sequence number as their ID
+		var err error
+		score, err = strconv.ParseFloat(jobIdStr, 64)
+		if err != nil {
+			return err
+		}
+	}
+	member := redis.Z{Score: score, Member: jobIdStr}
+	pipe.ZAdd("gifjob_index", member)
+	pipe.ZAdd(productIndexKey(job.ProductType), member)
+	pipe.ZAdd(sceneIndexKey(jobSceneId(job)), member)
+	for s := range pb.GetJobResponse_Status_name {
+		status := pb.GetJobResponse_Status(s)
+		if status == job.Status {
+			pipe.ZAdd(statusIndexKey(status), member)
+		} else {
+			pipe.ZRem(statusIndexKey(status), jobIdStr)
+		}
+	}
+	return nil
+}
+
+func (server) ListJobs(ctx context.Context, req *pb.ListJobsRequest) (*pb.ListJobsResponse, error) {
+	span := traceClient.NewSpan("gifCreator.ListJobs")
+	span.SetLabel("service", serviceName)
+	span.SetLabel("version", deploymentId)
+	defer span.Finish()
+
+	err := checkOperator(req.OperatorToken)
+	if err != nil {
+		return nil, err
+	}
+	pageSize := int64(req.PageSize)
+	if pageSize <= 0 {
+		pageSize = defaultListPageSize
+	}
+	if pageSize > maxListPageSize {
+		pageSize = maxListPageSize
+	}
+	max := "+inf"
+	if req.PageToken != "" {
+		if _, err := strconv.ParseFloat(req.PageToken, 64); err != nil {
+			return nil, grpc.Errorf(codes.InvalidArgument, "invalid page token %q", req.PageToken)
+		}
+		max = "(" + req.PageToken
+	}
+
+	indexKey := "gifjob_index"
+	filterProduct := req.Product != pb.Product_UNKNOWN_PRODUCT
+	filterScene := req.SceneId != ""
+	if req.Status != pb.GetJobResponse_UNKNOWN_STATUS {
+		indexKey = statusIndexKey(req.Status)
+	} else if filterScene {
+		indexKey = sceneIndexKey(req.SceneId)
+		filterScene = false
+	} else if filterProduct {
+		indexKey = productIndexKey(req.Product)
+		filterProduct = false
+	}
+
+	response := &pb.ListJobsResponse{}
+	for {
+		members, err := redisClient.ZRevRangeByScoreWithScores(indexKey, redis.ZRangeBy{
+			Min:   "-inf",
+			Max:   max,
+			Count: pageSize,
+		}).Result()
+		if err != nil {
+			return nil, err
+		}
+		if len(members) == 0 {
+			return response, nil
+		}
+		for _, member := range members {
+			jobIdStr := member.Member.(string)
+			token := strconv.FormatFloat(member.Score, 'f', -1, 64)
+			max = "(" + token
+
+			job, err := loadJob(jobIdStr)
+			if err == redis.Nil {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			if filterProduct && job.ProductType != req.Product {
+				continue
+			}
+			if filterScene && jobSceneId(job) != req.SceneId {
+				continue
+			}
+			response.Jobs = append(response.Jobs, &pb.JobSummary{
+				JobId:      jobIdStr,
+				Status:     job.Status,
+				Product:    job.ProductType,
+				Caption:    job.Caption,
+				CreateTime: job.StartTime,
+				SceneId:    jobSceneId(job),
+			})
+			if int64(len(response.Jobs)) == pageSize {
+				response.NextPageToken = token
+				return response, nil
+			}
+		}
+	}
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	pb "github.com/GoogleCloudPlatform/gifinator/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func TestListJobs(t *testing.T) {
+	_, cleanup := newTestRedis(t)
+	defer cleanup()
+	defer func(hash string) { operatorTokenHash = hash }(operatorTokenHash)
+	operatorTokenHash = hashOwnerToken("operator")
+
+	// Jobs 1 to 30 cycle through the products, and every third one is done
+	products := []pb.Product{pb.Product_GRPC, pb.Product_KUBERNETES, pb.Product_GO}
+	jobs := make(map[string]renderJob)
+	for id := 1; id <= 30; id++ {
+		job := renderJob{Status: pb.GetJobResponse_PENDING, ProductType: products[id%3], Caption: fmt.Sprint("job ", id)}
+		if id%3 == 0 {
+			job.Status = pb.GetJobResponse_DONE
+		}
+		// Index the job as pending first, so that the done jobs have moved
+		// between the status sets
+		if err := createJob(strconv.Itoa(id), renderJob{Status: pb.GetJobResponse_PENDING, ProductType: job.ProductType}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := updateJob(strconv.Itoa(id), func(saved *renderJob) { *saved = job }); err != nil {
+			t.Fatal(err)
+		}
+		jobs[strconv.Itoa(id)] = job
+	}
+
+	tests := []struct {
+		desc  string
+		req   pb.ListJobsRequest
+		pages int
+	}{
+		{"everything", pb.ListJobsRequest{}, 2},
+		{"small pages", pb.ListJobsRequest{PageSize: 7}, 5},
+		{"one big page", pb.ListJobsRequest{PageSize: 1000}, 1},
+		{"done", pb.ListJobsRequest{Status: pb.GetJobResponse_DONE, PageSize: 4}, 3},
+		{"pending", pb.ListJobsRequest{Status: pb.GetJobResponse_PENDING}, 2},
+		{"failed", pb.ListJobsRequest{Status: pb.GetJobResponse_FAILED}, 1},
+		{"one product", pb.ListJobsRequest{Product: pb.Product_GO, PageSize: 3}, 4},
+		{"one scene", pb.ListJobsRequest{SceneId: "k8s", PageSize: 6}, 2},
+		{"scene and status", pb.ListJobsRequest{Status: pb.GetJobResponse_DONE, SceneId: "grpc"}, 1},
+		{"product and status", pb.ListJobsRequest{Status: pb.GetJobResponse_PENDING, Product: pb.Product_KUBERNETES, PageSize: 4}, 3},
+	}
+	for _, tt := range tests {
+		var want []string
+		for id := 30; id >= 1; id-- {
+			job := jobs[strconv.Itoa(id)]
+			if tt.req.Status != pb.GetJobResponse_UNKNOWN_STATUS && job.Status != tt.req.Status {
+				continue
+			}
+			if tt.req.Product != pb.Product_UNKNOWN_PRODUCT && job.ProductType != tt.req.Product {
+				continue
+			}
+			if tt.req.SceneId != "" && jobSceneId(job) != tt.req.SceneId {
+				continue
+			}
+			want = append(want, strconv.Itoa(id))
+		}
+
+		var got []string
+		pages := 0
+		req := tt.req
+		req.OperatorToken = "operator"
+		for {
+			response, err := server{}.ListJobs(context.Background(), &req)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.desc, err)
+			}
+			pages++
+			for _, summary := range response.Jobs {
+				got = append(got, summary.JobId)
+				job := jobs[summary.JobId]
+				if summary.Status != job.Status || summary.Product != job.ProductType || summary.Caption != job.Caption {
+					t.Errorf("%s: summary %v does not match job %+v", tt.desc, summary, job)
+				}
+			}
+			if response.NextPageToken == "" {
+				break
+			}
+			req.PageToken = response.NextPageToken
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%s: listed %v, want %v", tt.desc, got, want)
+		}
+		if pages != tt.pages {
+			t.Errorf("%s: took %d pages, want %d", tt.desc, pages, tt.pages)
+		}
+	}
+
+	_, err := server{}.ListJobs(context.Background(), &pb.ListJobsRequest{OperatorToken: "operator", PageToken: "next"})
+	if code := grpc.Code(err); code != codes.InvalidArgument {
+		t.Errorf("ListJobs with a bad page token = %v, want %v", err, codes.InvalidArgument)
+	}
+}
+
+func TestListJobsNeedsOperator(t *testing.T) {
+	_, cleanup := newTestRedis(t)
+	defer cleanup()
+	defer func(hash string) { operatorTokenHash = hash }(operatorTokenHash)
+	tests := []struct {
+		desc     string
+		operator string
+		token    string
+		code     codes.Code
+	}{
+		{"operator", "operator", "operator", codes.OK},
+		{"wrong token", "operator", "guess", codes.PermissionDenied},
+		{"no token", "operator", "", codes.PermissionDenied},
+		{"listing disabled", "", "", codes.PermissionDenied},
+		{"listing disabled with a token", "", "operator", codes.PermissionDenied},
+	}
+	for _, tt := range tests {
+		operatorTokenHash = ""
+		if tt.operator != "" {
+			operatorTokenHash = hashOwnerToken(tt.operator)
+		}
+		_, err := server{}.ListJobs(context.Background(), &pb.ListJobsRequest{OperatorToken: tt.token})
+		if code := grpc.Code(err); code != tt.code {
+			t.Errorf("%s: ListJobs = %v, want %v", tt.desc, err, tt.code)
+		}
+	}
+}
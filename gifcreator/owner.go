@@ -0,0 +1,111 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/hex"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"gopkg.in/redis.v5"
+)
+
+/**
+ * Job IDs are 128 random bits written in lower case base32, so they can't be
+ * guessed, and never contain the "_" that separates job and task IDs in
+ * Redis. Jobs started before this have IDs taken from gifjob_counter. Those
+ * are all digits, which a random ID never is, and they keep working because
+ * every Redis key and blob name is built from the ID the same way.
+ *
+ * StartJob also hands out an owner token, which calls that change a job must
+ * present. Only its SHA-256 hash is kept, in the job record. Reading a job
+ * never needs it, so jobs from before owner tokens can still be viewed by
+ * anyone with their ID. Those jobs have no owner, so only an operator, with
+ * the operator token below, can change them.
+ *
+ * ListJobs would hand out every job ID, so it needs the operator token that
+ * the server is started with in OPERATOR_TOKEN instead. Without one, nobody
+ * can list jobs.
+ */
+
+// operatorTokenHash is the SHA-256 hash of OPERATOR_TOKEN, or empty if it is
+// unset.
+var operatorTokenHash string
+
+func newJobId() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	id := strings.TrimRight(base32.StdEncoding.EncodeToString(b), "=")
+	return strings.ToLower(id), nil
+}
+
+func newOwnerToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashOwnerToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// checkOwner returns an error unless token is the owner token of the job, in
+// which case the caller may change the job. Jobs started before owner tokens
+// existed have no owner, so only the operator token will do for them.
+func checkOwner(jobIdStr string, job renderJob, token string) error {
+	if job.OwnerTokenHash == "" {
+		if checkOperator(token) != nil {
+			return grpc.Errorf(codes.PermissionDenied, "job %s has no owner, so only an operator can change it", jobIdStr)
+		}
+		return nil
+	}
+	given := hashOwnerToken(token)
+	if subtle.ConstantTimeCompare([]byte(given), []byte(job.OwnerTokenHash)) != 1 {
+		return grpc.Errorf(codes.PermissionDenied, "wrong owner token for job %s", jobIdStr)
+	}
+	return nil
+}
+
+// checkOperator returns an error unless token is the operator token.
+func checkOperator(token string) error {
+	if operatorTokenHash == "" {
+		return grpc.Errorf(codes.PermissionDenied, "listing jobs is disabled: OPERATOR_TOKEN is not set")
+	}
+	given := hashOwnerToken(token)
+	if subtle.ConstantTimeCompare([]byte(given), []byte(operatorTokenHash)) != 1 {
+		return grpc.Errorf(codes.PermissionDenied, "wrong operator token")
+	}
+	return nil
+}
+
+// jobNotFound turns a missing job record into a NotFound error.
+func jobNotFound(jobIdStr string, err error) error {
+	if err == redis.Nil {
+		return grpc.Errorf(codes.NotFound, "no job %s", jobIdStr)
+	}
+	return err
+}
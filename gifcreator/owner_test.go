@@ -0,0 +1,82 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"regexp"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func TestNewJobId(t *testing.T) {
+	// 128 bits is 26 base32 characters once the padding is trimmed
+	valid := regexp.MustCompile(`^[a-z2-7]{26}$`)
+	digits := regexp.MustCompile(`^[0-9]+$`)
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		id, err := newJobId()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !valid.MatchString(id) {
+			t.Fatalf("newJobId() = %q, want 26 lower case base32 characters", id)
+		}
+		if digits.MatchString(id) {
+			t.Fatalf("newJobId() = %q, which could be mistaken for a legacy ID", id)
+		}
+		if seen[id] {
+			t.Fatalf("newJobId() returned %q twice", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestCheckOwner(t *testing.T) {
+	owned := renderJob{OwnerTokenHash: hashOwnerToken("owner")}
+	legacy := renderJob{}
+
+	tests := []struct {
+		desc     string
+		job      renderJob
+		token    string
+		operator string
+		want     codes.Code
+	}{
+		{"owner token", owned, "owner", "", codes.OK},
+		{"wrong token", owned, "someone else", "", codes.PermissionDenied},
+		{"no token", owned, "", "", codes.PermissionDenied},
+		{"owner token hash", owned, hashOwnerToken("owner"), "", codes.PermissionDenied},
+		{"operator token on an owned job", owned, "operator", "operator", codes.PermissionDenied},
+		{"legacy job without an operator", legacy, "", "", codes.PermissionDenied},
+		{"legacy job with a token", legacy, "owner", "", codes.PermissionDenied},
+		{"legacy job with the operator token", legacy, "operator", "operator", codes.OK},
+		{"legacy job with the wrong operator token", legacy, "owner", "operator", codes.PermissionDenied},
+	}
+	defer func(hash string) { operatorTokenHash = hash }(operatorTokenHash)
+	for _, tt := range tests {
+		operatorTokenHash = ""
+		if tt.operator != "" {
+			operatorTokenHash = hashOwnerToken(tt.operator)
+		}
+		err := checkOwner("job", tt.job, tt.token)
+		if got := grpc.Code(err); got != tt.want {
+			t.Errorf("%s: checkOwner = %v, want %v", tt.desc, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	pb "github.com/GoogleCloudPlatform/gifinator/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+/**
+ * Frames are rendered with RenderFrameProgressive, which writes each frame's
+ * earlier iterations to preview.<job>/ as it goes. Frames that take a single
+ * iteration, as DRAFT and STANDARD ones do, get one rough preview traced with
+ * a single sample per pixel first. The frames that have a preview are
+ * recorded in the previews_gifjob_<job> set, and the worker that adds the
+ * last one puts the previews together into preview.gif, so that users have a
+ * rough version of their GIF to look at long before the final one is ready.
+ *
+ * The preview's URL is kept in the image_url field of the
+ * preview_gifjob_<job> hash rather than in the job record, so that recording
+ * it never has to write the job record, and a preview that arrives late can
+ * never undo a change to the job.
+ *
+ * Tiled and batched frames, and frames of jobs with a draft, are rendered
+ * without previews.
+ */
+
+// previewPrefix is the prefix of the names of a job's preview frames.
+func previewPrefix(jobIdStr string) string {
+	return "preview." + jobIdStr + "/frame_"
+}
+
+// wantsPreviews reports whether a task's frame should be rendered with
+// previews. Jobs with a draft get their preview from that instead (see
+// drafts.go).
+func wantsPreviews(task renderTask) bool {
+	return task.TileSize == 0 && !task.Draft && !task.HasDraft
+}
+
+func previewKey(jobIdStr string) string {
+	return "preview_gifjob_" + jobIdStr
+}
+
+// renderWithPreviews renders a task's frame, recording its previews as they
+// arrive. If the render fails, trailer is set to the trailer the render
+// service sent with the error.
+func renderWithPreviews(tCtx context.Context, renderCtx context.Context, jobIdStr string, task renderTask, req *pb.RenderRequest, trailer *metadata.MD) error {
+	req.PreviewOutputBase = blobPath(fmt.Sprintf("%s%04d", previewPrefix(jobIdStr), task.Frame))
+	stream, err := renderClient.RenderFrameProgressive(renderCtx, req)
+	if err != nil {
+		return err
+	}
+	started := false
+	for {
+		progress, err := stream.Recv()
+		if grpc.Code(err) == codes.Unimplemented && !started {
+			// The render service is older than RenderFrameProgressive
+			_, err = renderClient.RenderFrame(renderCtx, req, grpc.Trailer(trailer))
+			return err
+		}
+		if err == io.EOF {
+			return fmt.Errorf("render service did not return the final image")
+		}
+		if err != nil {
+			*trailer = stream.Trailer()
+			return err
+		}
+		started = true
+		if progress.Final {
+			return nil
+		}
+		if progress.Iteration == 1 {
+			// A missing preview is no reason to fail the frame
+			if err := previewDone(tCtx, jobIdStr, task.Frame); err != nil {
+				fmt.Fprintf(os.Stderr, "error recording preview of frame %d of job_gifjob_%s: %v\n", task.Frame, jobIdStr, err)
+			}
+		}
+	}
+}
+
+// previewDone records that a frame has a preview, and puts the preview GIF
+// together once every frame has one.
+func previewDone(ctx context.Context, jobIdStr string, frame int64) error {
+	added, err := redisClient.SAdd("previews_gifjob_"+jobIdStr, frame).Result()
+	if err != nil || added == 0 {
+		return err
+	}
+	previews, err := redisClient.SCard("previews_gifjob_" + jobIdStr).Result()
+	if err != nil {
+		return err
+	}
+	job, err := loadJob(jobIdStr)
+	if err != nil {
+		return err
+	}
+	if previews < job.FramesTotal || jobFinished(job.Status) {
+		return nil
+	}
+
+	fmt.Fprintf(os.Stdout, "compiling preview of job_gifjob_%s\n", jobIdStr)
+	previewImagePath, err := encodeGif(ctx, previewPrefix(jobIdStr), "out."+jobIdStr+"/preview.gif", job.FrameDelay, nil)
+	if err != nil {
+		return err
+	}
+	err = redisClient.HSet(previewKey(jobIdStr), "image_url", previewImagePath).Err()
+	if err != nil {
+		return err
+	}
+	publishJobUpdate(jobIdStr)
+	return nil
+}
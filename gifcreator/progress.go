@@ -0,0 +1,157 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	pb "github.com/GoogleCloudPlatform/gifinator/proto"
+	"gopkg.in/redis.v5"
+)
+
+/**
+ * A job's stage and timestamps live in its job_gifjob_<id> record, and are
+ * moved forward by whoever is doing the work: StartJob while it prepares the
+ * assets, and the worker that completes the last frame while it compiles and
+ * uploads the GIF. The number of frames completed comes straight from the
+ * counter_completed_gifjob_<id> counter that the workers already keep.
+ *
+ * Several processes change a job at once: workers moving it through its
+ * stages, CancelJob, and whoever fails it. So every change after StartJob
+ * creates the record goes through updateJob, a check-and-set that WATCHes the
+ * record while it is read and changed, and retries if anyone else wrote it in
+ * the meantime. updateJob never changes a job that is DONE, FAILED or
+ * CANCELLED, so once a job has finished, nothing can set it going again.
+ */
+
+// errJobFinished is returned by updateJob for a job that has already
+// finished.
+var errJobFinished = errors.New("job has already finished")
+
+// jobFinished reports whether a job in the given status is finished, which
+// is for good.
+func jobFinished(status pb.GetJobResponse_Status) bool {
+	return status == pb.GetJobResponse_DONE ||
+		status == pb.GetJobResponse_FAILED ||
+		status == pb.GetJobResponse_CANCELLED
+}
+
+func loadJob(jobIdStr string) (renderJob, error) {
+	var job renderJob
+	payload, err := redisClient.Get("job_gifjob_" + jobIdStr).Result()
+	if err != nil {
+		return job, err
+	}
+	err = json.Unmarshal([]byte(payload), &job)
+	return job, err
+}
+
+// createJob writes the record of a new job.
+func createJob(jobIdStr string, job renderJob) error {
+	job.UpdateTime = time.Now().Unix()
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	_, err = redisClient.TxPipelined(func(pipe *redis.Pipeline) error {
+		pipe.Set("job_gifjob_"+jobIdStr, payload, 0)
+		return indexJob(pipe, jobIdStr, job)
+	})
+	if err != nil {
+		return err
+	}
+	publishJobUpdate(jobIdStr)
+	return nil
+}
+
+// updateJob changes a job record with update, atomically. It returns the job
+// as it was saved, or errJobFinished and the job as it is if the job has
+// already finished, in which case update is not called.
+func updateJob(jobIdStr string, update func(job *renderJob)) (renderJob, error) {
+	key := "job_gifjob_" + jobIdStr
+	for {
+		var job renderJob
+		err := redisClient.Watch(func(tx *redis.Tx) error {
+			payload, err := tx.Get(key).Result()
+			if err != nil {
+				return err
+			}
+			job = renderJob{}
+			err = json.Unmarshal([]byte(payload), &job)
+			if err != nil {
+				return err
+			}
+			if jobFinished(job.Status) {
+				return errJobFinished
+			}
+			update(&job)
+			job.UpdateTime = time.Now().Unix()
+			newPayload, err := json.Marshal(job)
+			if err != nil {
+				return err
+			}
+			// EXEC fails if the record has changed since the WATCH
+			_, err = tx.Pipelined(func(pipe *redis.Pipeline) error {
+				pipe.Set(key, newPayload, 0)
+				return indexJob(pipe, jobIdStr, job)
+			})
+			return err
+		}, key)
+		if err == redis.TxFailedErr {
+			continue
+		}
+		if err == nil {
+			publishJobUpdate(jobIdStr)
+		}
+		return job, err
+	}
+}
+
+// setJobStage records that a job has moved on to the given stage. It returns
+// errJobFinished if the job has finished instead.
+func setJobStage(jobIdStr string, stage pb.JobProgress_Stage) error {
+	_, err := updateJob(jobIdStr, func(job *renderJob) {
+		job.Stage = stage
+	})
+	return err
+}
+
+// jobProgress builds the progress report for a job that GetJob returns.
+func jobProgress(jobIdStr string, job renderJob) (*pb.JobProgress, error) {
+	completed, err := redisClient.Get("counter_completed_gifjob_" + jobIdStr).Result()
+	if err == redis.Nil {
+		completed = "0"
+	} else if err != nil {
+		return nil, err
+	}
+	completedInt, _ := strconv.ParseInt(completed, 10, 64)
+	if job.TilesPerFrame > 1 {
+		// Count frames, not tiles
+		completedInt /= int64(job.TilesPerFrame)
+	}
+	return &pb.JobProgress{
+		Stage:           job.Stage,
+		FramesCompleted: completedInt,
+		FramesTotal:     job.FramesTotal,
+		StartTime:       job.StartTime,
+		UpdateTime:      job.UpdateTime,
+		EndTime:         job.EndTime,
+	}, nil
+}
@@ -0,0 +1,73 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	pb "github.com/GoogleCloudPlatform/gifinator/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// renderSettings are the parts of a RenderRequest set by a quality preset.
+type renderSettings struct {
+	Width           int32
+	Height          int32
+	SamplesPerPixel int32
+	Iterations      int32
+}
+
+var qualityPresets = map[pb.Quality]renderSettings{
+	pb.Quality_DRAFT:    {Width: 150, Height: 150, SamplesPerPixel: 4, Iterations: 1},
+	pb.Quality_STANDARD: {Width: 300, Height: 300, SamplesPerPixel: 16, Iterations: 1},
+	pb.Quality_HIGH:     {Width: 600, Height: 600, SamplesPerPixel: 32, Iterations: 2},
+	pb.Quality_POSTER:   {Width: 2048, Height: 2048, SamplesPerPixel: 16, Iterations: 1},
+}
+
+// draftSettings are used for the drafts of jobs started with preview set.
+var draftSettings = renderSettings{Width: 100, Height: 100, SamplesPerPixel: 1, Iterations: 1}
+
+// settingsFor returns the render settings for a quality preset.
+func settingsFor(quality pb.Quality) renderSettings {
+	settings, ok := qualityPresets[quality]
+	if !ok {
+		// Tasks queued before quality presets existed
+		settings = qualityPresets[pb.Quality_STANDARD]
+	}
+	return settings
+}
+
+// maxQuality is the best quality this deployment will render. It is set from
+// MAX_QUALITY, so that a small cluster can refuse jobs that would tie up its
+// render fleet for hours. POSTER frames are split into tiles, each of which
+// costs less to render than a HIGH frame.
+var maxQuality = pb.Quality_POSTER
+
+// qualityFor resolves the quality a job asked for, and checks it against
+// maxQuality.
+func qualityFor(req *pb.StartJobRequest) (pb.Quality, error) {
+	quality := req.Quality
+	if quality == pb.Quality_UNKNOWN_QUALITY {
+		quality = pb.Quality_STANDARD
+	}
+	if _, ok := qualityPresets[quality]; !ok {
+		return quality, grpc.Errorf(codes.InvalidArgument, "unknown quality %v", quality)
+	}
+	if quality > maxQuality {
+		return quality, grpc.Errorf(codes.InvalidArgument, "quality %v is above this deployment's limit of %v", quality, maxQuality)
+	}
+	return quality, nil
+}
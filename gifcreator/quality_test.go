@@ -0,0 +1,73 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"testing"
+
+	pb "github.com/GoogleCloudPlatform/gifinator/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func TestQualityFor(t *testing.T) {
+	defer func(max pb.Quality) { maxQuality = max }(maxQuality)
+	tests := []struct {
+		requested pb.Quality
+		max       pb.Quality
+		want      pb.Quality
+		code      codes.Code
+	}{
+		{pb.Quality_UNKNOWN_QUALITY, pb.Quality_HIGH, pb.Quality_STANDARD, codes.OK},
+		{pb.Quality_DRAFT, pb.Quality_HIGH, pb.Quality_DRAFT, codes.OK},
+		{pb.Quality_STANDARD, pb.Quality_HIGH, pb.Quality_STANDARD, codes.OK},
+		{pb.Quality_HIGH, pb.Quality_HIGH, pb.Quality_HIGH, codes.OK},
+		{pb.Quality_HIGH, pb.Quality_STANDARD, pb.Quality_HIGH, codes.InvalidArgument},
+		{pb.Quality_POSTER, pb.Quality_POSTER, pb.Quality_POSTER, codes.OK},
+		{pb.Quality_POSTER, pb.Quality_HIGH, pb.Quality_POSTER, codes.InvalidArgument},
+		{pb.Quality_UNKNOWN_QUALITY, pb.Quality_DRAFT, pb.Quality_STANDARD, codes.InvalidArgument},
+		{pb.Quality(42), pb.Quality_HIGH, pb.Quality(42), codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		maxQuality = tt.max
+		got, err := qualityFor(&pb.StartJobRequest{Quality: tt.requested})
+		if code := grpc.Code(err); code != tt.code {
+			t.Errorf("qualityFor(%v) with a limit of %v = %v, want %v", tt.requested, tt.max, err, tt.code)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("qualityFor(%v) with a limit of %v = %v, want %v", tt.requested, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestQualityPresetsWithinRenderLimits(t *testing.T) {
+	// The render service's default limits, see checkRenderSettings. Frames
+	// bigger than tileSize are rendered a tile at a time.
+	for quality, settings := range qualityPresets {
+		width, height := settings.Width, settings.Height
+		if width > tileSize {
+			width = tileSize
+		}
+		if height > tileSize {
+			height = tileSize
+		}
+		if width > 1024 || height > 1024 || settings.SamplesPerPixel > 64 || settings.Iterations > 4 {
+			t.Errorf("%v preset %+v is beyond the render service's default limits", quality, settings)
+		}
+	}
+}
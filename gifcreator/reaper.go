@@ -0,0 +1,142 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/redis.v5"
+)
+
+/**
+ * Every task in gifjob_processing has a lease recorded in the gifjob_leases
+ * hash, keyed by the same "<job>_<task>" string. The worker holding the lease
+ * renews it while the frame renders. If a worker crashes, its leases stop
+ * being renewed, and once they are older than leaseTimeout the reaper counts
+ * that as a failed attempt and hands the task to the retry policy in retry.go,
+ * which puts it back on gifjob_queued for another worker to pick up.
+ */
+
+type taskLease struct {
+	WorkerId string
+	LeasedAt time.Time
+}
+
+var (
+	workerId     string
+	leaseTimeout = 5 * time.Minute
+	reapInterval = time.Minute
+)
+
+func recordLease(jobString string) error {
+	payload, err := json.Marshal(taskLease{WorkerId: workerId, LeasedAt: time.Now()})
+	if err != nil {
+		return err
+	}
+	return redisClient.HSet("gifjob_leases", jobString, payload).Err()
+}
+
+func releaseLease(jobString string) error {
+	return redisClient.HDel("gifjob_leases", jobString).Err()
+}
+
+// renewLease keeps the lease on jobString fresh, so that slow renders are not
+// mistaken for crashed ones, until the returned function is called. That
+// function waits for any renewal in flight, so that none can land after the
+// lease is released.
+func renewLease(jobString string) (stop func()) {
+	stopping := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(leaseTimeout / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopping:
+				return
+			case <-ticker.C:
+				if err := recordLease(jobString); err != nil {
+					fmt.Fprintf(os.Stderr, "error renewing lease on gifjob_%s: %v\n", jobString, err)
+				}
+			}
+		}
+	}()
+	return func() {
+		close(stopping)
+		<-done
+	}
+}
+
+// reapExpiredLeases requeues every task in gifjob_processing whose lease is
+// older than leaseTimeout. It is safe to run from several workers at once.
+func reapExpiredLeases() error {
+	processing, err := redisClient.LRange("gifjob_processing", 0, -1).Result()
+	if err != nil {
+		return err
+	}
+	for _, jobString := range processing {
+		payload, err := redisClient.HGet("gifjob_leases", jobString).Result()
+		if err == redis.Nil {
+			// A worker can die between leasing a task and recording the lease.
+			// Start the clock now, so the task is reaped one timeout from now.
+			lease, _ := json.Marshal(taskLease{LeasedAt: time.Now()})
+			if err := redisClient.HSetNX("gifjob_leases", jobString, lease).Err(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		var lease taskLease
+		if err := json.Unmarshal([]byte(payload), &lease); err != nil {
+			return err
+		}
+		if time.Since(lease.LeasedAt) < leaseTimeout {
+			continue
+		}
+
+		// Only the reaper that manages to remove the entry requeues it, so
+		// concurrent sweeps never duplicate a task.
+		removed, err := redisClient.LRem("gifjob_processing", 1, jobString).Result()
+		if err != nil {
+			return err
+		}
+		if removed == 0 {
+			continue
+		}
+		fmt.Fprintf(os.Stdout, "reaped gifjob_%s leased by %q at %v\n", jobString, lease.WorkerId, lease.LeasedAt)
+		cause := fmt.Errorf("lease held by %q expired", lease.WorkerId)
+		if err := failTask(jobString, cause); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runReaper() {
+	for {
+		time.Sleep(reapInterval)
+		if err := reapExpiredLeases(); err != nil {
+			fmt.Fprintf(os.Stderr, "error reaping leases: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestReapExpiredLeases(t *testing.T) {
+	mr, cleanup := newTestRedis(t)
+	defer cleanup()
+
+	tests := []struct {
+		desc      string
+		jobString string
+		leasedAgo time.Duration // 0 means no lease was recorded
+		requeued  bool
+	}{
+		{"fresh lease", "1_0", time.Second, false},
+		{"nearly expired lease", "1_1", leaseTimeout - time.Minute, false},
+		{"expired lease", "1_2", leaseTimeout + time.Second, true},
+		{"long expired lease", "2_0", 24 * time.Hour, true},
+		{"no lease", "2_1", 0, false},
+	}
+	for _, tt := range tests {
+		mr.Push("gifjob_processing", tt.jobString)
+		mr.Set("task_gifjob_"+tt.jobString, `{"Frame":0}`)
+		if tt.leasedAgo != 0 {
+			lease, _ := json.Marshal(taskLease{WorkerId: "worker", LeasedAt: time.Now().Add(-tt.leasedAgo)})
+			mr.HSet("gifjob_leases", tt.jobString, string(lease))
+		}
+	}
+
+	if err := reapExpiredLeases(); err != nil {
+		t.Fatal(err)
+	}
+	processing, _ := mr.List("gifjob_processing")
+	delayed, _ := mr.ZMembers("gifjob_delayed")
+	for _, tt := range tests {
+		inProcessing := contains(processing, tt.jobString)
+		inDelayed := contains(delayed, tt.jobString)
+		if inProcessing == tt.requeued || inDelayed != tt.requeued {
+			t.Errorf("%s: processing %v, delayed %v, want requeued %v", tt.desc, inProcessing, inDelayed, tt.requeued)
+		}
+		lease := mr.HGet("gifjob_leases", tt.jobString)
+		if tt.requeued && lease != "" {
+			t.Errorf("%s: lease %s left behind", tt.desc, lease)
+		}
+		if !tt.requeued && lease == "" {
+			t.Errorf("%s: no lease after the sweep", tt.desc)
+		}
+	}
+
+	// A second sweep finds nothing more to do.
+	if err := reapExpiredLeases(); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := mr.ZMembers("gifjob_delayed"); len(again) != len(delayed) {
+		t.Errorf("second sweep delayed %v, want %v", again, delayed)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRenewLease(t *testing.T) {
+	mr, cleanup := newTestRedis(t)
+	defer cleanup()
+	defer func(d time.Duration) { leaseTimeout = d }(leaseTimeout)
+	leaseTimeout = 30 * time.Millisecond
+
+	stop := renewLease("1_0")
+	time.Sleep(3 * leaseTimeout / 2)
+	if mr.HGet("gifjob_leases", "1_0") == "" {
+		t.Errorf("lease not renewed")
+	}
+	stop()
+	if err := releaseLease("1_0"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(leaseTimeout)
+	if lease := mr.HGet("gifjob_leases", "1_0"); lease != "" {
+		t.Errorf("lease %s renewed after it was released", lease)
+	}
+}
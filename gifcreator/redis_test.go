@@ -0,0 +1,40 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis"
+	"gopkg.in/redis.v5"
+)
+
+// newTestRedis points redisClient at an in-memory Redis server for the
+// length of a test. Call the returned function to shut it down.
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, func()) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := redisClient
+	redisClient = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	return mr, func() {
+		redisClient.Close()
+		redisClient = old
+		mr.Close()
+	}
+}
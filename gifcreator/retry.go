@@ -0,0 +1,211 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/GoogleCloudPlatform/gifinator/proto"
+	"google.golang.org/grpc/metadata"
+	"gopkg.in/redis.v5"
+)
+
+/**
+ * A task that fails, either because RenderFrame returned an error or because
+ * its lease expired, goes back onto the queue after an exponential backoff.
+ * While it waits it sits in the gifjob_delayed sorted set, scored by the unix
+ * time it becomes ready again. A task that fails maxTaskAttempts times is moved
+ * to gifjob_deadletter instead, and its job is marked as FAILED.
+ */
+
+var (
+	maxTaskAttempts = 5
+	retryBackoff    = 2 * time.Second
+	maxRetryBackoff = 5 * time.Minute
+)
+
+// backoffFor returns how long to wait before retrying a task that has failed
+// attempts times.
+func backoffFor(attempts int) time.Duration {
+	d := retryBackoff
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= maxRetryBackoff {
+			return maxRetryBackoff
+		}
+	}
+	return d
+}
+
+// retryAfter returns how long the render service asked for a refused render
+// to wait in its retry-after-ms trailer, or retryBackoff if it didn't say.
+func retryAfter(trailer metadata.MD) time.Duration {
+	for _, v := range trailer["retry-after-ms"] {
+		ms, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || ms < 0 {
+			continue
+		}
+		d := time.Duration(ms) * time.Millisecond
+		if d > maxRetryBackoff {
+			return maxRetryBackoff
+		}
+		return d
+	}
+	return retryBackoff
+}
+
+// failTask records a failed attempt at a task that the caller has already
+// removed from gifjob_processing, and either schedules a retry or dead-letters
+// the task.
+func failTask(jobString string, cause error) error {
+	if isDraft(jobString) {
+		fmt.Fprintf(os.Stderr, "dropped gifjob_%s: %v\n", jobString, cause)
+		return releaseLease(jobString)
+	}
+	strs := strings.Split(jobString, "_")
+	jobIdStr := strs[0]
+	taskIdStr := strs[1]
+	taskKey := "task_gifjob_" + jobIdStr + "_" + taskIdStr
+
+	payload, err := redisClient.Get(taskKey).Result()
+	if err != nil {
+		return err
+	}
+	var task renderTask
+	err = json.Unmarshal([]byte(payload), &task)
+	if err != nil {
+		return err
+	}
+	task.Attempts++
+	newPayload, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	err = redisClient.Set(taskKey, newPayload, 0).Err()
+	if err != nil {
+		return err
+	}
+	err = releaseLease(jobString)
+	if err != nil {
+		return err
+	}
+
+	if task.Attempts >= maxTaskAttempts {
+		err = redisClient.LPush("gifjob_deadletter", jobString).Err()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "dead-lettered gifjob_%s after %d attempts: %v\n", jobString, task.Attempts, cause)
+		return markJobFailed(jobIdStr, &pb.JobError{
+			Stage:   pb.JobError_RENDER_FRAME,
+			Frame:   task.Frame,
+			Message: fmt.Sprintf("failed %d times: %v", task.Attempts, cause),
+		})
+	}
+
+	backoff := backoffFor(task.Attempts)
+	readyAt := time.Now().Add(backoff)
+	err = redisClient.ZAdd("gifjob_delayed", redis.Z{Score: float64(readyAt.Unix()), Member: jobString}).Err()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "retrying gifjob_%s in %v (attempt %d of %d): %v\n", jobString, backoff, task.Attempts+1, maxTaskAttempts, cause)
+	return nil
+}
+
+// deferTask puts a task that the caller has removed from gifjob_processing
+// back on the queue after delay, without counting it as a failed attempt.
+func deferTask(jobString string, delay time.Duration) error {
+	err := releaseLease(jobString)
+	if err != nil {
+		return err
+	}
+	readyAt := time.Now().Add(delay)
+	err = redisClient.ZAdd("gifjob_delayed", redis.Z{Score: float64(readyAt.Unix()), Member: jobString}).Err()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "deferring gifjob_%s for %v\n", jobString, delay)
+	return nil
+}
+
+// markJobFailed moves a job to the FAILED state, recording why for GetJob.
+func markJobFailed(jobIdStr string, jobErr *pb.JobError) error {
+	_, err := updateJob(jobIdStr, func(job *renderJob) {
+		job.Status = pb.GetJobResponse_FAILED
+		job.Error = jobErr
+		job.EndTime = time.Now().Unix()
+	})
+	if err == errJobFinished {
+		// Keep whatever finished the job first: the first error is the one
+		// that sank it, and a cancelled job stays cancelled.
+		return nil
+	}
+	return err
+}
+
+// jobStopped reports whether the job has already failed or been cancelled,
+// in which case there is no point rendering any more of its frames.
+func jobStopped(jobIdStr string) (bool, error) {
+	job, err := loadJob(jobIdStr)
+	if err != nil {
+		return false, err
+	}
+	return job.Status == pb.GetJobResponse_FAILED || job.Status == pb.GetJobResponse_CANCELLED, nil
+}
+
+// promoteDelayedTasks moves tasks whose backoff has elapsed from
+// gifjob_delayed back onto gifjob_queued, or gifjob_draft_queued for drafts.
+// It is safe to run from several workers at once.
+func promoteDelayedTasks() error {
+	due, err := redisClient.ZRangeByScore("gifjob_delayed", redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(time.Now().Unix(), 10),
+	}).Result()
+	if err != nil {
+		return err
+	}
+	for _, jobString := range due {
+		removed, err := redisClient.ZRem("gifjob_delayed", jobString).Result()
+		if err != nil {
+			return err
+		}
+		if removed == 0 {
+			continue
+		}
+		err = redisClient.RPush(queueFor(jobString), jobString).Err()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "requeued gifjob_%s after backoff\n", jobString)
+	}
+	return nil
+}
+
+func runRetryScheduler() {
+	for {
+		time.Sleep(time.Second)
+		if err := promoteDelayedTasks(); err != nil {
+			fmt.Fprintf(os.Stderr, "error requeueing delayed tasks: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/GoogleCloudPlatform/gifinator/proto"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestBackoffFor(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, retryBackoff},
+		{1, retryBackoff},
+		{2, 2 * retryBackoff},
+		{3, 4 * retryBackoff},
+		{5, 16 * retryBackoff},
+		{8, 128 * retryBackoff},
+		{9, maxRetryBackoff},
+		{100, maxRetryBackoff},
+	}
+	for _, tt := range tests {
+		if got := backoffFor(tt.attempts); got != tt.want {
+			t.Errorf("backoffFor(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestFailTask(t *testing.T) {
+	mr, cleanup := newTestRedis(t)
+	defer cleanup()
+	mr.Set("job_gifjob_1", `{"Status":1}`)
+	mr.Set("task_gifjob_1_0", `{"Frame":3}`)
+
+	for attempt := 1; attempt <= maxTaskAttempts; attempt++ {
+		mr.HSet("gifjob_leases", "1_0", `{}`)
+		mr.Del("gifjob_delayed")
+		before := time.Now()
+		if err := failTask("1_0", errors.New("render failed")); err != nil {
+			t.Fatalf("attempt %d: %v", attempt, err)
+		}
+
+		var task renderTask
+		payload, _ := mr.Get("task_gifjob_1_0")
+		json.Unmarshal([]byte(payload), &task)
+		if task.Attempts != attempt {
+			t.Errorf("attempt %d: task records %d attempts", attempt, task.Attempts)
+		}
+		if lease := mr.HGet("gifjob_leases", "1_0"); lease != "" {
+			t.Errorf("attempt %d: lease %s not released", attempt, lease)
+		}
+		deadletter, _ := mr.List("gifjob_deadletter")
+		if attempt < maxTaskAttempts {
+			readyAt, err := mr.ZScore("gifjob_delayed", "1_0")
+			if err != nil {
+				t.Errorf("attempt %d: task not delayed: %v", attempt, err)
+				continue
+			}
+			want := before.Add(backoffFor(attempt)).Unix()
+			if int64(readyAt) < want || int64(readyAt) > want+1 {
+				t.Errorf("attempt %d: task ready at %v, want %v", attempt, int64(readyAt), want)
+			}
+			if len(deadletter) != 0 {
+				t.Errorf("attempt %d: dead-lettered %v too early", attempt, deadletter)
+			}
+			continue
+		}
+		if members, _ := mr.ZMembers("gifjob_delayed"); len(members) != 0 {
+			t.Errorf("last attempt: task delayed again")
+		}
+		if len(deadletter) != 1 || deadletter[0] != "1_0" {
+			t.Errorf("last attempt: gifjob_deadletter = %v, want [1_0]", deadletter)
+		}
+		var job renderJob
+		payload, _ = mr.Get("job_gifjob_1")
+		json.Unmarshal([]byte(payload), &job)
+		if job.Status != pb.GetJobResponse_FAILED || job.Error == nil {
+			t.Fatalf("last attempt: job is %v with error %v", job.Status, job.Error)
+		}
+		if job.Error.Stage != pb.JobError_RENDER_FRAME || job.Error.Frame != 3 || !strings.Contains(job.Error.Message, "render failed") {
+			t.Errorf("last attempt: job error is %v", job.Error)
+		}
+	}
+}
+
+func TestMarkJobFailed(t *testing.T) {
+	mr, cleanup := newTestRedis(t)
+	defer cleanup()
+	first := &pb.JobError{Stage: pb.JobError_UPLOAD_ASSETS, Message: "first"}
+	second := &pb.JobError{Stage: pb.JobError_COMPILE_GIF, Message: "second"}
+	tests := []struct {
+		desc   string
+		job    string
+		errs   []*pb.JobError
+		status pb.GetJobResponse_Status
+		want   *pb.JobError
+	}{
+		{"pending job", `{"Status":1}`, []*pb.JobError{first}, pb.GetJobResponse_FAILED, first},
+		{"failed twice", `{"Status":1}`, []*pb.JobError{first, second}, pb.GetJobResponse_FAILED, first},
+		{"failed without details", `{"Status":1}`, []*pb.JobError{nil, second}, pb.GetJobResponse_FAILED, nil},
+		{"cancelled job", `{"Status":4}`, []*pb.JobError{first}, pb.GetJobResponse_CANCELLED, nil},
+	}
+	for _, tt := range tests {
+		mr.Set("job_gifjob_1", tt.job)
+		for _, jobErr := range tt.errs {
+			if err := markJobFailed("1", jobErr); err != nil {
+				t.Fatalf("%s: %v", tt.desc, err)
+			}
+		}
+		var job renderJob
+		payload, _ := mr.Get("job_gifjob_1")
+		json.Unmarshal([]byte(payload), &job)
+		if job.Status != tt.status {
+			t.Errorf("%s: job is %v, want %v", tt.desc, job.Status, tt.status)
+		}
+		if (job.Error == nil) != (tt.want == nil) || job.Error != nil && job.Error.String() != tt.want.String() {
+			t.Errorf("%s: job error is %v, want %v", tt.desc, job.Error, tt.want)
+		}
+	}
+	if err := markJobFailed("2", first); err == nil {
+		t.Errorf("markJobFailed of a missing job succeeded")
+	}
+}
+
+func TestPromoteDelayedTasks(t *testing.T) {
+	mr, cleanup := newTestRedis(t)
+	defer cleanup()
+	now := time.Now().Unix()
+	mr.ZAdd("gifjob_delayed", float64(now-60), "1_0")
+	mr.ZAdd("gifjob_delayed", float64(now), "1_1")
+	mr.ZAdd("gifjob_delayed", float64(now+60), "1_2")
+
+	if err := promoteDelayedTasks(); err != nil {
+		t.Fatal(err)
+	}
+	queued, _ := mr.List("gifjob_queued")
+	delayed, _ := mr.ZMembers("gifjob_delayed")
+	if strings.Join(queued, ",") != "1_0,1_1" || strings.Join(delayed, ",") != "1_2" {
+		t.Errorf("queued %v and delayed %v, want [1_0 1_1] and [1_2]", queued, delayed)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		desc    string
+		trailer metadata.MD
+		want    time.Duration
+	}{
+		{"no trailer", nil, retryBackoff},
+		{"other trailers", metadata.Pairs("other", "1"), retryBackoff},
+		{"retry-after-ms", metadata.Pairs("retry-after-ms", "1500"), 1500 * time.Millisecond},
+		{"zero", metadata.Pairs("retry-after-ms", "0"), 0},
+		{"capped", metadata.Pairs("retry-after-ms", "86400000"), maxRetryBackoff},
+		{"garbled", metadata.Pairs("retry-after-ms", "soon"), retryBackoff},
+		{"negative", metadata.Pairs("retry-after-ms", "-5"), retryBackoff},
+		{"first valid value", metadata.Pairs("retry-after-ms", "x", "retry-after-ms", "250"), 250 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := retryAfter(tt.trailer); got != tt.want {
+			t.Errorf("%s: retryAfter = %v, want %v", tt.desc, got, tt.want)
+		}
+	}
+}
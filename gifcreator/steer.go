@@ -0,0 +1,264 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	pb "github.com/GoogleCloudPlatform/gifinator/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+/**
+ * With RENDER_PEERS_NAME set to a DNS name that resolves to every render
+ * replica, such as a headless Kubernetes service, a worker connects to each
+ * replica itself instead of going through RENDER_NAME. It asks each replica
+ * for its load with GetLoad, and sends each render to the one with the most
+ * room, so that work goes where it can start soonest instead of queueing
+ * behind a busy replica. Replicas that don't answer in time are passed over.
+ *
+ * A reading is reused for loadCacheInterval rather than asking again before
+ * every render, and every render sent to a replica in the meantime is added
+ * to its reading, so that a burst of renders is still spread out. The name is
+ * resolved again every renderPeersRefreshInterval, to pick up replicas as
+ * they come and go.
+ */
+
+var (
+	renderPeersRefreshInterval = 30 * time.Second
+	loadProbeTimeout           = 500 * time.Millisecond
+	loadCacheInterval          = 2 * time.Second
+)
+
+// renderPool is a pb.RenderClient that sends each call to the least busy of
+// several render replicas.
+type renderPool struct {
+	name     string
+	port     string
+	dialOpts []grpc.DialOption
+
+	mu    sync.Mutex
+	conns map[string]*grpc.ClientConn // by address
+	loads map[string]*loadReading     // by address
+}
+
+// loadReading is what a replica last said about its load, plus the renders
+// sent to it since.
+type loadReading struct {
+	busy      int32 // renders running and waiting
+	maxActive int32
+	at        time.Time
+}
+
+func newRenderPool(name string, port string, dialOpts ...grpc.DialOption) (*renderPool, error) {
+	p := &renderPool{
+		name:     name,
+		port:     port,
+		dialOpts: dialOpts,
+		conns:    make(map[string]*grpc.ClientConn),
+		loads:    make(map[string]*loadReading),
+	}
+	if err := p.refresh(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// refresh connects to replicas that have appeared since the last refresh, and
+// disconnects from those that have gone.
+func (p *renderPool) refresh() error {
+	hosts, err := net.LookupHost(p.name)
+	if err != nil {
+		return err
+	}
+	current := make(map[string]bool)
+	for _, host := range hosts {
+		current[net.JoinHostPort(host, p.port)] = true
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for addr := range current {
+		if _, ok := p.conns[addr]; ok {
+			continue
+		}
+		conn, err := grpc.Dial(addr, p.dialOpts...)
+		if err != nil {
+			return err
+		}
+		p.conns[addr] = conn
+		fmt.Fprintf(os.Stdout, "connected to render replica %s\n", addr)
+	}
+	for addr, conn := range p.conns {
+		if !current[addr] {
+			conn.Close()
+			delete(p.conns, addr)
+			delete(p.loads, addr)
+			fmt.Fprintf(os.Stdout, "disconnected from render replica %s\n", addr)
+		}
+	}
+	return nil
+}
+
+func (p *renderPool) run() {
+	for {
+		time.Sleep(renderPeersRefreshInterval)
+		if err := p.refresh(); err != nil {
+			fmt.Fprintf(os.Stderr, "error refreshing render replicas: %v\n", err)
+		}
+	}
+}
+
+// pick returns a client for the replica with the most room, judged by how
+// many renders each has running and waiting for every render it runs at
+// once. Ties are broken at random, so that workers don't all pile onto the
+// same idle replica.
+func (p *renderPool) pick(ctx context.Context) (pb.RenderClient, error) {
+	p.mu.Lock()
+	addrs := make([]string, 0, len(p.conns))
+	for addr := range p.conns {
+		addrs = append(addrs, addr)
+	}
+	p.mu.Unlock()
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no render replicas found at %s", p.name)
+	}
+	if len(addrs) == 1 {
+		return p.client(addrs[0])
+	}
+	shuffled := make([]string, len(addrs))
+	for i, j := range rand.Perm(len(addrs)) {
+		shuffled[i] = addrs[j]
+	}
+	addrs = shuffled
+
+	p.probe(ctx, addrs)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	best := ""
+	var bestBusyness float64
+	for _, addr := range addrs {
+		reading, ok := p.loads[addr]
+		if !ok {
+			continue
+		}
+		busyness := float64(reading.busy) / float64(reading.maxActive)
+		if best == "" || busyness < bestBusyness {
+			best, bestBusyness = addr, busyness
+		}
+	}
+	if best == "" {
+		// Nobody answered in time; any replica is as good as another
+		best = addrs[0]
+	} else {
+		p.loads[best].busy++
+	}
+	return p.clientLocked(best)
+}
+
+// probe asks the replicas whose readings are missing or out of date for
+// their load.
+func (p *renderPool) probe(ctx context.Context, addrs []string) {
+	now := time.Now()
+	var stale []string
+	p.mu.Lock()
+	for _, addr := range addrs {
+		reading, ok := p.loads[addr]
+		if !ok || now.Sub(reading.at) >= loadCacheInterval {
+			delete(p.loads, addr)
+			stale = append(stale, addr)
+		}
+	}
+	p.mu.Unlock()
+
+	probeCtx, cancel := context.WithTimeout(ctx, loadProbeTimeout)
+	defer cancel()
+	var wg sync.WaitGroup
+	for _, addr := range stale {
+		client, err := p.client(addr)
+		if err != nil {
+			continue
+		}
+		wg.Add(1)
+		go func(addr string, client pb.RenderClient) {
+			defer wg.Done()
+			load, err := client.GetLoad(probeCtx, &pb.GetLoadRequest{})
+			if err != nil || load.MaxActive <= 0 {
+				return
+			}
+			p.mu.Lock()
+			p.loads[addr] = &loadReading{busy: load.Active + load.Queued, maxActive: load.MaxActive, at: now}
+			p.mu.Unlock()
+		}(addr, client)
+	}
+	wg.Wait()
+}
+
+// client returns a client for the replica at addr.
+func (p *renderPool) client(addr string) (pb.RenderClient, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.clientLocked(addr)
+}
+
+// clientLocked is client with p.mu held.
+func (p *renderPool) clientLocked(addr string) (pb.RenderClient, error) {
+	conn, ok := p.conns[addr]
+	if !ok {
+		return nil, fmt.Errorf("render replica %s has gone", addr)
+	}
+	return pb.NewRenderClient(conn), nil
+}
+
+func (p *renderPool) RenderFrame(ctx context.Context, in *pb.RenderRequest, opts ...grpc.CallOption) (*pb.RenderResponse, error) {
+	client, err := p.pick(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.RenderFrame(ctx, in, opts...)
+}
+
+func (p *renderPool) RenderFrames(ctx context.Context, in *pb.RenderFramesRequest, opts ...grpc.CallOption) (pb.Render_RenderFramesClient, error) {
+	client, err := p.pick(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.RenderFrames(ctx, in, opts...)
+}
+
+func (p *renderPool) RenderFrameProgressive(ctx context.Context, in *pb.RenderRequest, opts ...grpc.CallOption) (pb.Render_RenderFrameProgressiveClient, error) {
+	client, err := p.pick(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.RenderFrameProgressive(ctx, in, opts...)
+}
+
+func (p *renderPool) GetLoad(ctx context.Context, in *pb.GetLoadRequest, opts ...grpc.CallOption) (*pb.RenderLoad, error) {
+	client, err := p.pick(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetLoad(ctx, in, opts...)
+}
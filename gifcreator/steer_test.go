@@ -0,0 +1,152 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/GoogleCloudPlatform/gifinator/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// fakeLoadServer is a render replica that only answers GetLoad.
+type fakeLoadServer struct {
+	pb.RenderServer
+	load pb.RenderLoad
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (s *fakeLoadServer) GetLoad(ctx context.Context, req *pb.GetLoadRequest) (*pb.RenderLoad, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	load := s.load
+	return &load, nil
+}
+
+func (s *fakeLoadServer) probes() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+// newTestPool starts a replica for each load, and returns a pool of them and
+// their addresses. Call the returned function to shut them down.
+func newTestPool(t *testing.T, loads ...pb.RenderLoad) (*renderPool, []*fakeLoadServer, []string, func()) {
+	p := &renderPool{
+		name:  "render-peers",
+		conns: make(map[string]*grpc.ClientConn),
+		loads: make(map[string]*loadReading),
+	}
+	var servers []*fakeLoadServer
+	var addrs []string
+	var stops []func()
+	for _, load := range loads {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fake := &fakeLoadServer{load: load}
+		srv := grpc.NewServer()
+		pb.RegisterRenderServer(srv, fake)
+		go srv.Serve(l)
+		conn, err := grpc.Dial(l.Addr().String(), grpc.WithInsecure())
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.conns[l.Addr().String()] = conn
+		servers = append(servers, fake)
+		addrs = append(addrs, l.Addr().String())
+		stops = append(stops, func() {
+			conn.Close()
+			srv.Stop()
+		})
+	}
+	return p, servers, addrs, func() {
+		for _, stop := range stops {
+			stop()
+		}
+	}
+}
+
+func TestPickCachesLoad(t *testing.T) {
+	defer func(d time.Duration) { loadCacheInterval = d }(loadCacheInterval)
+	loadCacheInterval = time.Hour
+	p, servers, addrs, cleanup := newTestPool(t,
+		pb.RenderLoad{Active: 1, MaxActive: 1},
+		pb.RenderLoad{Active: 0, MaxActive: 2})
+	defer cleanup()
+	ctx := context.Background()
+
+	// The idle replica takes renders until it is as busy as the other
+	tests := []struct {
+		busy   []int32
+		probes int
+	}{
+		{[]int32{1, 1}, 1},
+		{[]int32{1, 2}, 1},
+	}
+	for i, tt := range tests {
+		if _, err := p.pick(ctx); err != nil {
+			t.Fatalf("pick %d = %v", i, err)
+		}
+		for j, addr := range addrs {
+			if got := p.loads[addr].busy; got != tt.busy[j] {
+				t.Errorf("pick %d: replica %d has %d renders, want %d", i, j, got, tt.busy[j])
+			}
+			if got := servers[j].probes(); got != tt.probes {
+				t.Errorf("pick %d: replica %d probed %d times, want %d", i, j, got, tt.probes)
+			}
+		}
+	}
+
+	// Once the readings are out of date they are taken again
+	loadCacheInterval = 0
+	if _, err := p.pick(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for j := range addrs {
+		if got := servers[j].probes(); got != 2 {
+			t.Errorf("replica %d probed %d times after the readings expired, want 2", j, got)
+		}
+	}
+}
+
+func TestPickSkipsSilentReplicas(t *testing.T) {
+	p, _, addrs, cleanup := newTestPool(t,
+		pb.RenderLoad{Active: 4, MaxActive: 1},
+		pb.RenderLoad{Active: 0, MaxActive: 0})
+	defer cleanup()
+
+	// The second replica doesn't report a limit, so the busy first one is
+	// the only one with a reading
+	if _, err := p.pick(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if reading := p.loads[addrs[0]]; reading == nil || reading.busy != 5 {
+		t.Errorf("busy replica has reading %+v, want 5 renders", reading)
+	}
+	if reading := p.loads[addrs[1]]; reading != nil {
+		t.Errorf("silent replica has reading %+v", reading)
+	}
+}
@@ -0,0 +1,151 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+	"os"
+
+	"github.com/GoogleCloudPlatform/gifinator/internal/blobstore"
+	"github.com/GoogleCloudPlatform/gifinator/internal/gcsref"
+	pb "github.com/GoogleCloudPlatform/gifinator/proto"
+	"golang.org/x/net/context"
+)
+
+/**
+ * Frames wider or taller than TILE_SIZE (default 1024) pixels are split into
+ * tiles of at most TILE_SIZE by TILE_SIZE, and each tile is queued as a task
+ * of its own, so that the whole render fleet can work on one large frame.
+ * Tiles are written under tiles.<job>/, and the worker that completes the
+ * last tile of a frame stitches them into the frame that compileGifs
+ * expects. Each frame counts its finished tiles in
+ * counter_tiles_gifjob_<job>_<frame>.
+ */
+
+// tileSize is the largest tile, in pixels along each side, that a frame is
+// split into. Frames are not split if it is 0.
+var tileSize int32 = 1024
+
+// tileRegions splits a width by height image into tiles of at most size by
+// size pixels, in rows from the top left.
+func tileRegions(width, height, size int32) []*pb.Region {
+	var regions []*pb.Region
+	for y := int32(0); y < height; y += size {
+		for x := int32(0); x < width; x += size {
+			regions = append(regions, &pb.Region{
+				X:      x,
+				Y:      y,
+				Width:  min32(size, width-x),
+				Height: min32(size, height-y),
+			})
+		}
+	}
+	return regions
+}
+
+func min32(a, b int32) int32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// region returns the part of the frame a task renders, or nil for all of it.
+func (task renderTask) region() *pb.Region {
+	if task.TileSize == 0 {
+		return nil
+	}
+	settings := settingsFor(task.Quality)
+	return tileRegions(settings.Width, settings.Height, task.TileSize)[task.Tile]
+}
+
+// tilePrefix is the prefix of the names of a frame's tiles.
+func tilePrefix(jobIdStr string, frame int64) string {
+	return fmt.Sprintf("tiles.%s/frame_%04d_tile_", jobIdStr, frame)
+}
+
+// outputBaseFor returns where the render service should write a task's
+// image.
+func outputBaseFor(jobIdStr string, task renderTask) string {
+	if task.Draft {
+		return blobPath(fmt.Sprintf("%s%04d", previewPrefix(jobIdStr), task.Frame))
+	}
+	if task.TileSize == 0 {
+		return blobPath(frameName(jobIdStr, task.Frame))
+	}
+	return blobPath(fmt.Sprintf("%s%04d", tilePrefix(jobIdStr, task.Frame), task.Tile))
+}
+
+// tileDone counts a finished tile, and stitches its frame together if it was
+// the frame's last.
+func tileDone(ctx context.Context, jobIdStr string, task renderTask) error {
+	settings := settingsFor(task.Quality)
+	regions := tileRegions(settings.Width, settings.Height, task.TileSize)
+	done, err := redisClient.Incr(fmt.Sprintf("counter_tiles_gifjob_%s_%d", jobIdStr, task.Frame)).Result()
+	if err != nil {
+		return err
+	}
+	if done < int64(len(regions)) {
+		return nil
+	}
+	fmt.Fprintf(os.Stdout, "stitching frame %d of job_gifjob_%s\n", task.Frame, jobIdStr)
+	return stitchFrame(ctx, jobIdStr, task.Frame, settings, regions)
+}
+
+// stitchFrame puts the tiles of a frame together and stores the frame.
+func stitchFrame(ctx context.Context, jobIdStr string, frame int64, settings renderSettings, regions []*pb.Region) error {
+	// The store returns objects ordered by name, which is tile order
+	tiles, err := blobStore.List(ctx, gcsref.MustParseRef(blobPath(tilePrefix(jobIdStr, frame))))
+	if err != nil {
+		return err
+	}
+	if len(tiles) != len(regions) {
+		return fmt.Errorf("found %d tiles of frame %d, expected %d", len(tiles), frame, len(regions))
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, int(settings.Width), int(settings.Height)))
+	for i, tile := range tiles {
+		rc, err := blobStore.Get(ctx, tile.Ref)
+		if err != nil {
+			return err
+		}
+		tileImg, err := png.Decode(rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		r := regions[i]
+		dst := image.Rect(int(r.X), int(r.Y), int(r.X+r.Width), int(r.Y+r.Height))
+		if tileImg.Bounds().Size() != dst.Size() {
+			return fmt.Errorf("tile %v is %v, expected %v", tile.Ref, tileImg.Bounds().Size(), dst.Size())
+		}
+		draw.Draw(img, dst, tileImg, tileImg.Bounds().Min, draw.Src)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(png.Encode(pw, img))
+	}()
+	frameRef := gcsref.MustParseRef(blobPath(frameName(jobIdStr, frame) + ".png"))
+	err = blobstore.Upload(ctx, blobStore, frameRef, pr, "image/png")
+	pr.CloseWithError(err)
+	return err
+}
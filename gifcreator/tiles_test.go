@@ -0,0 +1,183 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/gifinator/internal/blobstore"
+	"github.com/GoogleCloudPlatform/gifinator/internal/gcsref"
+	pb "github.com/GoogleCloudPlatform/gifinator/proto"
+	"golang.org/x/net/context"
+)
+
+func TestTileRegions(t *testing.T) {
+	tests := []struct {
+		width, height, size int32
+		want                []pb.Region
+	}{
+		{100, 100, 100, []pb.Region{{X: 0, Y: 0, Width: 100, Height: 100}}},
+		{100, 100, 200, []pb.Region{{X: 0, Y: 0, Width: 100, Height: 100}}},
+		{200, 100, 100, []pb.Region{
+			{X: 0, Y: 0, Width: 100, Height: 100},
+			{X: 100, Y: 0, Width: 100, Height: 100},
+		}},
+		{250, 150, 100, []pb.Region{
+			{X: 0, Y: 0, Width: 100, Height: 100},
+			{X: 100, Y: 0, Width: 100, Height: 100},
+			{X: 200, Y: 0, Width: 50, Height: 100},
+			{X: 0, Y: 100, Width: 100, Height: 50},
+			{X: 100, Y: 100, Width: 100, Height: 50},
+			{X: 200, Y: 100, Width: 50, Height: 50},
+		}},
+		{2048, 2048, 1024, []pb.Region{
+			{X: 0, Y: 0, Width: 1024, Height: 1024},
+			{X: 1024, Y: 0, Width: 1024, Height: 1024},
+			{X: 0, Y: 1024, Width: 1024, Height: 1024},
+			{X: 1024, Y: 1024, Width: 1024, Height: 1024},
+		}},
+	}
+	for _, tt := range tests {
+		var got []pb.Region
+		for _, r := range tileRegions(tt.width, tt.height, tt.size) {
+			got = append(got, *r)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tileRegions(%d, %d, %d) = %v, want %v", tt.width, tt.height, tt.size, got, tt.want)
+		}
+	}
+}
+
+// TestPosterIsTiled checks that the default tile size splits the presets
+// that are too big for a render pod, and only those.
+func TestPosterIsTiled(t *testing.T) {
+	for quality, settings := range qualityPresets {
+		tiled := settings.Width > tileSize || settings.Height > tileSize
+		if want := quality == pb.Quality_POSTER; tiled != want {
+			t.Errorf("%v frames are %dx%d, tiled %v with tiles of %d; want tiled %v", quality, settings.Width, settings.Height, tiled, tileSize, want)
+		}
+	}
+}
+
+// useTempBlobStore points the blob store at a local store in a new temporary
+// directory, and returns a function that puts everything back.
+func useTempBlobStore(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "gifcreator-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldStore, oldConfig, oldBucket := blobStore, blobConfig, gcsBucketName
+	blobConfig = blobstore.Config{Backend: blobstore.BackendLocal, LocalDir: dir}
+	blobStore = blobstore.NewLocal(dir, "")
+	gcsBucketName = "bucket"
+	return func() {
+		blobStore, blobConfig, gcsBucketName = oldStore, oldConfig, oldBucket
+		os.RemoveAll(dir)
+	}
+}
+
+// tileColor is the colour that a tile is filled with in the tests.
+func tileColor(i int) color.NRGBA {
+	return color.NRGBA{R: uint8(40 * i), G: uint8(255 - 40*i), B: 128, A: 255}
+}
+
+// putTile stores a tile of the given size filled with tileColor(i).
+func putTile(t *testing.T, ctx context.Context, jobIdStr string, frame int64, i int, width, height int) {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{tileColor(i)}, image.ZP, draw.Src)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	ref := gcsref.MustParseRef(blobPath(fmt.Sprintf("%s%04d.png", tilePrefix(jobIdStr, frame), i)))
+	if err := blobStore.Put(ctx, ref, &buf, "image/png"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStitchFrame(t *testing.T) {
+	defer useTempBlobStore(t)()
+	ctx := context.Background()
+
+	settings := renderSettings{Width: 250, Height: 150}
+	regions := tileRegions(settings.Width, settings.Height, 100)
+	for i, r := range regions {
+		putTile(t, ctx, "job", 3, i, int(r.Width), int(r.Height))
+	}
+	if err := stitchFrame(ctx, "job", 3, settings, regions); err != nil {
+		t.Fatal(err)
+	}
+
+	rc, err := blobStore.Get(ctx, gcsref.MustParseRef(blobPath(frameName("job", 3)+".png")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	img, err := png.Decode(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := img.Bounds(); got != image.Rect(0, 0, 250, 150) {
+		t.Fatalf("frame is %v, want 250x150", got)
+	}
+	for i, r := range regions {
+		// Check the corners of each tile
+		for _, p := range []image.Point{
+			{int(r.X), int(r.Y)},
+			{int(r.X + r.Width - 1), int(r.Y + r.Height - 1)},
+		} {
+			got := color.NRGBAModel.Convert(img.At(p.X, p.Y))
+			if got != tileColor(i) {
+				t.Errorf("pixel %v = %v, want %v from tile %d", p, got, tileColor(i), i)
+			}
+		}
+	}
+}
+
+func TestStitchFrameErrors(t *testing.T) {
+	settings := renderSettings{Width: 200, Height: 100}
+	regions := tileRegions(settings.Width, settings.Height, 100)
+	tests := []struct {
+		desc  string
+		tiles []image.Point // the size of each tile to store
+	}{
+		{"missing tile", []image.Point{{100, 100}}},
+		{"extra tile", []image.Point{{100, 100}, {100, 100}, {100, 100}}},
+		{"wrong size", []image.Point{{100, 100}, {50, 100}}},
+	}
+	for _, tt := range tests {
+		func() {
+			defer useTempBlobStore(t)()
+			ctx := context.Background()
+			for i, size := range tt.tiles {
+				putTile(t, ctx, "job", 0, i, size.X, size.Y)
+			}
+			if err := stitchFrame(ctx, "job", 0, settings, regions); err == nil {
+				t.Errorf("%s: stitchFrame succeeded, want an error", tt.desc)
+			}
+		}()
+	}
+}
@@ -0,0 +1,128 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	pb "github.com/GoogleCloudPlatform/gifinator/proto"
+	"gopkg.in/redis.v5"
+)
+
+/**
+ * Whenever a job changes, the process that changed it publishes the job ID on
+ * the gifjob_events_<id> Redis channel. WatchJob subscribes to that channel,
+ * so a watcher connected to any gifcreator replica hears about frames that
+ * were rendered by any worker. The messages carry no state of their own; the
+ * watcher reads the job back from Redis each time, so a burst of events
+ * collapses into one read.
+ */
+
+func jobEventsChannel(jobIdStr string) string {
+	return "gifjob_events_" + jobIdStr
+}
+
+// publishJobUpdate tells anyone watching a job that it has changed. A lost
+// event only delays watchers until the next one, so errors are logged rather
+// than failing the work that caused them.
+func publishJobUpdate(jobIdStr string) {
+	err := redisClient.Publish(jobEventsChannel(jobIdStr), jobIdStr).Err()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error publishing update to job_gifjob_%s: %v\n", jobIdStr, err)
+	}
+}
+
+// jobStatus reads back everything that GetJob and WatchJob report on a job.
+func jobStatus(jobIdStr string) (*pb.GetJobResponse, error) {
+	job, err := loadJob(jobIdStr)
+	if err != nil {
+		return nil, jobNotFound(jobIdStr, err)
+	}
+	progress, err := jobProgress(jobIdStr, job)
+	if err != nil {
+		return nil, err
+	}
+	previewImageUrl, err := redisClient.HGet(previewKey(jobIdStr), "image_url").Result()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+	return &pb.GetJobResponse{
+		ImageUrl:        job.FinalImagePath,
+		Status:          job.Status,
+		Error:           job.Error,
+		Progress:        progress,
+		PreviewImageUrl: previewImageUrl,
+	}, nil
+}
+
+func (server) WatchJob(req *pb.WatchJobRequest, stream pb.GifCreator_WatchJobServer) error {
+	span := traceClient.NewSpan("gifCreator.WatchJob")
+	span.SetLabel("service", serviceName)
+	span.SetLabel("version", deploymentId)
+	defer span.Finish()
+
+	// Subscribe before the first read, so that nothing can change in between
+	// without us hearing about it.
+	pubsub, err := redisClient.Subscribe(jobEventsChannel(req.JobId))
+	if err != nil {
+		return err
+	}
+	defer pubsub.Close()
+
+	events := make(chan *redis.Message)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(events)
+		for {
+			msg, err := pubsub.ReceiveMessage()
+			if err != nil {
+				return
+			}
+			select {
+			case events <- msg:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	for {
+		response, err := jobStatus(req.JobId)
+		if err != nil {
+			return err
+		}
+		err = stream.Send(response)
+		if err != nil {
+			return err
+		}
+		if response.Status != pb.GetJobResponse_PENDING {
+			return nil
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case _, ok := <-events:
+			if !ok {
+				return errors.New("lost subscription to job_gifjob_" + req.JobId)
+			}
+		}
+	}
+}
@@ -0,0 +1,166 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package blobstore abstracts the object storage used to pass scene assets,
+// rendered frames and finished GIFs between the gifinator services.
+package blobstore
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/GoogleCloudPlatform/gifinator/internal/gcsref"
+	"golang.org/x/net/context"
+)
+
+// ErrNotExist is returned when a referenced object does not exist.
+var ErrNotExist = errors.New("blobstore: object does not exist")
+
+// BlobStore is a bucketed object store.
+type BlobStore interface {
+	// Get opens the object for reading. The caller must close the reader.
+	Get(ctx context.Context, ref gcsref.Ref) (io.ReadCloser, error)
+
+	// Put writes the contents of r to the object, replacing any existing
+	// object with the same reference.
+	Put(ctx context.Context, ref gcsref.Ref, r io.Reader, contentType string) error
+
+	// List returns the attributes of every object in the bucket of prefix
+	// whose name starts with prefix.Name, ordered by name.
+	List(ctx context.Context, prefix gcsref.Ref) ([]Attrs, error)
+
+	// Delete removes the object. It returns ErrNotExist if there is no such
+	// object.
+	Delete(ctx context.Context, ref gcsref.Ref) error
+
+	// Stat returns the attributes of the object.
+	Stat(ctx context.Context, ref gcsref.Ref) (*Attrs, error)
+
+	// Publish makes the object world-readable and returns a URL that a
+	// browser can load it from.
+	Publish(ctx context.Context, ref gcsref.Ref) (string, error)
+}
+
+// Attrs describes a stored object.
+type Attrs struct {
+	Ref         gcsref.Ref
+	Size        int64
+	ContentType string
+	Updated     time.Time
+
+	// Version changes whenever the contents of the object do, so it can be
+	// used to tell whether a copy of the object is still current. It is
+	// opaque, and only comparable between objects of the same store.
+	Version string
+
+	// MD5 is the MD5 hash of the object's contents, or nil if the store
+	// does not keep one for it.
+	MD5 []byte
+}
+
+// Backends understood by Open.
+const (
+	BackendGCS   = "gcs"
+	BackendS3    = "s3"
+	BackendLocal = "local"
+)
+
+// Config selects and configures a BlobStore backend.
+type Config struct {
+	// Backend is BackendGCS, BackendS3 or BackendLocal. The empty string
+	// means GCS.
+	Backend string
+
+	// LocalDir is the root directory of the local backend. Each bucket is a
+	// directory directly underneath it.
+	LocalDir string
+
+	// LocalURL is the URL prefix that Publish returns for the local backend.
+	// It defaults to "/blobs", which the frontend serves from LocalDir.
+	LocalURL string
+
+	// S3Endpoint is the host[:port] of the S3-compatible service, such as
+	// "s3.amazonaws.com" or "localhost:9000" for a local MinIO.
+	S3Endpoint string
+
+	// S3AccessKeyID and S3SecretAccessKey are the credentials for the S3
+	// backend.
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+
+	// S3Region is the bucket region. It may be left empty for MinIO.
+	S3Region string
+
+	// S3Insecure disables TLS when talking to S3Endpoint.
+	S3Insecure bool
+}
+
+// ConfigFromEnv reads a Config from the BLOB_STORE, BLOB_LOCAL_DIR,
+// BLOB_LOCAL_URL, S3_ENDPOINT, S3_ACCESS_KEY_ID, S3_SECRET_ACCESS_KEY,
+// S3_REGION and S3_INSECURE environment variables.
+func ConfigFromEnv() Config {
+	return Config{
+		Backend:           os.Getenv("BLOB_STORE"),
+		LocalDir:          os.Getenv("BLOB_LOCAL_DIR"),
+		LocalURL:          os.Getenv("BLOB_LOCAL_URL"),
+		S3Endpoint:        os.Getenv("S3_ENDPOINT"),
+		S3AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+		S3SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+		S3Region:          os.Getenv("S3_REGION"),
+		S3Insecure:        os.Getenv("S3_INSECURE") == "true",
+	}
+}
+
+// Scheme returns the URI scheme of references held by the configured
+// backend.
+func (c Config) Scheme() gcsref.Scheme {
+	switch c.Backend {
+	case BackendS3:
+		return gcsref.SchemeS3
+	case BackendLocal:
+		return gcsref.SchemeFile
+	}
+	return gcsref.SchemeGCS
+}
+
+// Open returns the backend selected by cfg.
+func Open(ctx context.Context, cfg Config) (BlobStore, error) {
+	switch cfg.Backend {
+	case "", BackendGCS:
+		return NewGCS(ctx)
+	case BackendS3:
+		if cfg.S3Endpoint == "" {
+			return nil, errors.New("blobstore: S3 backend needs an endpoint")
+		}
+		return NewS3(cfg.S3Endpoint, cfg.S3AccessKeyID, cfg.S3SecretAccessKey, cfg.S3Region, !cfg.S3Insecure)
+	case BackendLocal:
+		if cfg.LocalDir == "" {
+			return nil, errors.New("blobstore: local backend needs a directory")
+		}
+		return NewLocal(cfg.LocalDir, cfg.LocalURL), nil
+	}
+	return nil, fmt.Errorf("blobstore: unknown backend %q", cfg.Backend)
+}
+
+func checkScheme(ref gcsref.Ref, want gcsref.Scheme) error {
+	if ref.Scheme != want {
+		return fmt.Errorf("blobstore: %v is not a %q reference", ref, string(want))
+	}
+	return nil
+}
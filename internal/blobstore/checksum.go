@@ -0,0 +1,69 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package blobstore
+
+import (
+	"bytes"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/GoogleCloudPlatform/gifinator/internal/gcsref"
+	"golang.org/x/net/context"
+)
+
+// ErrChecksum is returned when the data read or written for an object does
+// not match the checksum that the store holds for it.
+var ErrChecksum = errors.New("blobstore: checksum mismatch")
+
+// Download streams the object described by attrs into w. If the store keeps
+// an MD5 hash of the object, the data is checked against it.
+func Download(ctx context.Context, s BlobStore, attrs *Attrs, w io.Writer) error {
+	rc, err := s.Get(ctx, attrs.Ref)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	h := md5.New()
+	if _, err := io.Copy(io.MultiWriter(w, h), rc); err != nil {
+		return err
+	}
+	return checkMD5(attrs, h.Sum(nil))
+}
+
+// Upload streams r into the object, replacing any existing object with the
+// same reference. If the store keeps an MD5 hash of the object it wrote, it
+// is checked against the data read from r.
+func Upload(ctx context.Context, s BlobStore, ref gcsref.Ref, r io.Reader, contentType string) error {
+	h := md5.New()
+	if err := s.Put(ctx, ref, io.TeeReader(r, h), contentType); err != nil {
+		return err
+	}
+	attrs, err := s.Stat(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return checkMD5(attrs, h.Sum(nil))
+}
+
+func checkMD5(attrs *Attrs, sum []byte) error {
+	if attrs.MD5 == nil || bytes.Equal(attrs.MD5, sum) {
+		return nil
+	}
+	return fmt.Errorf("%v: %v", attrs.Ref, ErrChecksum)
+}
@@ -0,0 +1,58 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package blobstore
+
+import (
+	"bytes"
+	"crypto/md5"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/gifinator/internal/gcsref"
+	"golang.org/x/net/context"
+)
+
+func TestDownloadChecksum(t *testing.T) {
+	s, cleanup := newTestLocal(t)
+	defer cleanup()
+	ctx := context.Background()
+	ref := gcsref.MustParseRef("file://bucket/frame.png")
+	if err := Upload(ctx, s, ref, strings.NewReader("pixels"), "image/png"); err != nil {
+		t.Fatalf("Upload = %v", err)
+	}
+	good := md5.Sum([]byte("pixels"))
+	bad := md5.Sum([]byte("other pixels"))
+	tests := []struct {
+		desc string
+		md5  []byte
+		ok   bool
+	}{
+		{"no checksum", nil, true},
+		{"matching checksum", good[:], true},
+		{"mismatched checksum", bad[:], false},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		err := Download(ctx, s, &Attrs{Ref: ref, MD5: tt.md5}, &buf)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: Download = %v", tt.desc, err)
+		}
+		if buf.String() != "pixels" {
+			t.Errorf("%s: downloaded %q", tt.desc, buf.String())
+		}
+	}
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package blobstore
+
+import (
+	"io"
+	"strconv"
+
+	"cloud.google.com/go/storage"
+	"github.com/GoogleCloudPlatform/gifinator/internal/gcsref"
+	"golang.org/x/net/context"
+	"google.golang.org/api/iterator"
+)
+
+type gcsStore struct {
+	client *storage.Client
+}
+
+// NewGCS returns a BlobStore backed by Google Cloud Storage. It accepts only
+// "gs://" references.
+func NewGCS(ctx context.Context) (BlobStore, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &gcsStore{client: client}, nil
+}
+
+func (s *gcsStore) object(ref gcsref.Ref) (*storage.ObjectHandle, error) {
+	if err := checkScheme(ref, gcsref.SchemeGCS); err != nil {
+		return nil, err
+	}
+	return s.client.Bucket(ref.Bucket).Object(ref.Name), nil
+}
+
+func (s *gcsStore) Get(ctx context.Context, ref gcsref.Ref) (io.ReadCloser, error) {
+	obj, err := s.object(ref)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := obj.NewReader(ctx)
+	if err == storage.ErrObjectNotExist {
+		return nil, ErrNotExist
+	}
+	return rc, err
+}
+
+func (s *gcsStore) Put(ctx context.Context, ref gcsref.Ref, r io.Reader, contentType string) error {
+	obj, err := s.object(ref)
+	if err != nil {
+		return err
+	}
+	// Cancelling the writer's context abandons the upload, rather than
+	// leaving a partial object behind when r fails part way through
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	wc := obj.NewWriter(ctx)
+	wc.ObjectAttrs.ContentType = contentType
+	if _, err := io.Copy(wc, r); err != nil {
+		cancel()
+		wc.Close()
+		return err
+	}
+	return wc.Close()
+}
+
+func (s *gcsStore) List(ctx context.Context, prefix gcsref.Ref) ([]Attrs, error) {
+	if err := checkScheme(prefix, gcsref.SchemeGCS); err != nil {
+		return nil, err
+	}
+	it := s.client.Bucket(prefix.Bucket).Objects(ctx, &storage.Query{Prefix: prefix.Name, Versions: false})
+	var list []Attrs
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, gcsAttrs(attrs))
+	}
+	// Cloud Storage lists objects in lexicographic order already.
+	return list, nil
+}
+
+func (s *gcsStore) Delete(ctx context.Context, ref gcsref.Ref) error {
+	obj, err := s.object(ref)
+	if err != nil {
+		return err
+	}
+	err = obj.Delete(ctx)
+	if err == storage.ErrObjectNotExist {
+		return ErrNotExist
+	}
+	return err
+}
+
+func (s *gcsStore) Stat(ctx context.Context, ref gcsref.Ref) (*Attrs, error) {
+	obj, err := s.object(ref)
+	if err != nil {
+		return nil, err
+	}
+	attrs, err := obj.Attrs(ctx)
+	if err == storage.ErrObjectNotExist {
+		return nil, ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	a := gcsAttrs(attrs)
+	return &a, nil
+}
+
+func (s *gcsStore) Publish(ctx context.Context, ref gcsref.Ref) (string, error) {
+	obj, err := s.object(ref)
+	if err != nil {
+		return "", err
+	}
+	if err := obj.ACL().Set(ctx, storage.AllUsers, storage.RoleReader); err != nil {
+		return "", err
+	}
+	return ref.Object().DownloadURL().String(), nil
+}
+
+func gcsAttrs(attrs *storage.ObjectAttrs) Attrs {
+	return Attrs{
+		Ref:         gcsref.Ref{Scheme: gcsref.SchemeGCS, Bucket: attrs.Bucket, Name: attrs.Name},
+		Size:        attrs.Size,
+		ContentType: attrs.ContentType,
+		Updated:     attrs.Updated,
+		Version:     strconv.FormatInt(attrs.Generation, 10),
+		MD5:         attrs.MD5,
+	}
+}
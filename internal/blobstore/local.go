@@ -0,0 +1,172 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package blobstore
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/gifinator/internal/gcsref"
+	"golang.org/x/net/context"
+)
+
+type localStore struct {
+	root    string
+	baseURL string
+}
+
+// NewLocal returns a BlobStore that keeps objects as files underneath root,
+// one directory per bucket. It accepts only "file://" references. Publish
+// returns URLs beneath baseURL, or beneath "/blobs" if baseURL is empty.
+func NewLocal(root, baseURL string) BlobStore {
+	if baseURL == "" {
+		baseURL = "/blobs"
+	}
+	return &localStore{root: root, baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+func (s *localStore) path(ref gcsref.Ref) (string, error) {
+	if err := checkScheme(ref, gcsref.SchemeFile); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, ref.Bucket, filepath.FromSlash(ref.Name)), nil
+}
+
+func (s *localStore) Get(ctx context.Context, ref gcsref.Ref) (io.ReadCloser, error) {
+	p, err := s.path(ref)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return nil, ErrNotExist
+	}
+	return f, err
+}
+
+func (s *localStore) Put(ctx context.Context, ref gcsref.Ref, r io.Reader, contentType string) error {
+	p, err := s.path(ref)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0777); err != nil {
+		return err
+	}
+	// Write to a temporary file first so that readers never observe a
+	// partially written object.
+	f, err := ioutil.TempFile(filepath.Dir(p), ".put-")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), p)
+}
+
+func (s *localStore) List(ctx context.Context, prefix gcsref.Ref) ([]Attrs, error) {
+	if err := checkScheme(prefix, gcsref.SchemeFile); err != nil {
+		return nil, err
+	}
+	bucketDir := filepath.Join(s.root, prefix.Bucket)
+	var list []Attrs
+	// filepath.Walk visits files in lexical order, so the list comes out
+	// sorted by name.
+	err := filepath.Walk(bucketDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == bucketDir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".put-") {
+			return nil
+		}
+		rel, err := filepath.Rel(bucketDir, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if !strings.HasPrefix(name, prefix.Name) {
+			return nil
+		}
+		ref := gcsref.Ref{Scheme: gcsref.SchemeFile, Bucket: prefix.Bucket, Name: name}
+		list = append(list, localAttrs(ref, info))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *localStore) Delete(ctx context.Context, ref gcsref.Ref) error {
+	p, err := s.path(ref)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(p)
+	if os.IsNotExist(err) {
+		return ErrNotExist
+	}
+	return err
+}
+
+func (s *localStore) Stat(ctx context.Context, ref gcsref.Ref) (*Attrs, error) {
+	p, err := s.path(ref)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(p)
+	if os.IsNotExist(err) {
+		return nil, ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	a := localAttrs(ref, info)
+	return &a, nil
+}
+
+func (s *localStore) Publish(ctx context.Context, ref gcsref.Ref) (string, error) {
+	if _, err := s.Stat(ctx, ref); err != nil {
+		return "", err
+	}
+	return s.baseURL + "/" + ref.Bucket + "/" + ref.Name, nil
+}
+
+func localAttrs(ref gcsref.Ref, info os.FileInfo) Attrs {
+	return Attrs{
+		Ref:         ref,
+		Size:        info.Size(),
+		ContentType: mime.TypeByExtension(path.Ext(ref.Name)),
+		Updated:     info.ModTime(),
+		Version:     fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size()),
+	}
+}
@@ -0,0 +1,148 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package blobstore
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/gifinator/internal/gcsref"
+	"golang.org/x/net/context"
+)
+
+func newTestLocal(t *testing.T) (BlobStore, func()) {
+	dir, err := ioutil.TempDir("", "blobstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewLocal(dir, ""), func() { os.RemoveAll(dir) }
+}
+
+func TestLocalRoundTrip(t *testing.T) {
+	s, cleanup := newTestLocal(t)
+	defer cleanup()
+	ctx := context.Background()
+	ref := gcsref.MustParseRef("file://bucket/dir/frame.png")
+
+	if _, err := s.Get(ctx, ref); err != ErrNotExist {
+		t.Fatalf("Get before Put = %v, want ErrNotExist", err)
+	}
+	if err := s.Put(ctx, ref, strings.NewReader("pixels"), "image/png"); err != nil {
+		t.Fatal(err)
+	}
+	r, err := s.Get(ctx, ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil || string(got) != "pixels" {
+		t.Errorf("Get = %q, %v, want %q", got, err, "pixels")
+	}
+	a, err := s.Stat(ctx, ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Ref != ref || a.Size != 6 || a.ContentType != "image/png" {
+		t.Errorf("Stat = %+v", a)
+	}
+	url, err := s.Publish(ctx, ref)
+	if err != nil || url != "/blobs/bucket/dir/frame.png" {
+		t.Errorf("Publish = %q, %v", url, err)
+	}
+	if err := s.Delete(ctx, ref); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(ctx, ref); err != ErrNotExist {
+		t.Errorf("second Delete = %v, want ErrNotExist", err)
+	}
+	if _, err := s.Stat(ctx, ref); err != ErrNotExist {
+		t.Errorf("Stat after Delete = %v, want ErrNotExist", err)
+	}
+}
+
+func TestLocalList(t *testing.T) {
+	s, cleanup := newTestLocal(t)
+	defer cleanup()
+	ctx := context.Background()
+	for _, name := range []string{"b/2", "a/1", "b/1", "c"} {
+		ref := gcsref.Ref{Scheme: gcsref.SchemeFile, Bucket: "bucket", Name: name}
+		if err := s.Put(ctx, ref, bytes.NewReader(nil), ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{"", []string{"a/1", "b/1", "b/2", "c"}},
+		{"b/", []string{"b/1", "b/2"}},
+		{"a/1", []string{"a/1"}},
+		{"d", nil},
+	}
+	for _, tt := range tests {
+		list, err := s.List(ctx, gcsref.Ref{Scheme: gcsref.SchemeFile, Bucket: "bucket", Name: tt.prefix})
+		if err != nil {
+			t.Errorf("List(%q) = %v", tt.prefix, err)
+			continue
+		}
+		var got []string
+		for _, a := range list {
+			got = append(got, a.Ref.Name)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("List(%q) = %v, want %v", tt.prefix, got, tt.want)
+		}
+	}
+	list, err := s.List(ctx, gcsref.MustParseRef("file://empty/x"))
+	if err != nil || len(list) != 0 {
+		t.Errorf("List of a missing bucket = %v, %v, want nothing", list, err)
+	}
+}
+
+func TestLocalRejectsOtherSchemes(t *testing.T) {
+	s, cleanup := newTestLocal(t)
+	defer cleanup()
+	ref := gcsref.MustParseRef("gs://bucket/object")
+	if err := s.Put(context.Background(), ref, strings.NewReader(""), ""); err == nil {
+		t.Errorf("Put(%v) succeeded, want an error", ref)
+	}
+}
+
+func TestLocalVersion(t *testing.T) {
+	s, cleanup := newTestLocal(t)
+	defer cleanup()
+	ctx := context.Background()
+	ref := gcsref.MustParseRef("file://bucket/scene.mtl")
+	var versions []string
+	for _, contents := range []string{"first", "second version"} {
+		if err := s.Put(ctx, ref, strings.NewReader(contents), ""); err != nil {
+			t.Fatal(err)
+		}
+		a, err := s.Stat(ctx, ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		versions = append(versions, a.Version)
+	}
+	if versions[0] == "" || versions[0] == versions[1] {
+		t.Errorf("versions %q do not change with the contents", versions)
+	}
+}
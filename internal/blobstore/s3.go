@@ -0,0 +1,207 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package blobstore
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/gifinator/internal/gcsref"
+	"github.com/minio/minio-go"
+	"golang.org/x/net/context"
+)
+
+// s3PublishExpiry is how long the URLs returned by Publish stay valid. Seven
+// days is the longest that S3 allows for a presigned URL.
+const s3PublishExpiry = 7 * 24 * time.Hour
+
+type s3Store struct {
+	client *minio.Client
+}
+
+// NewS3 returns a BlobStore backed by an S3-compatible service such as
+// Amazon S3 or MinIO. It accepts only "s3://" references. Pointing endpoint at
+// a local MinIO, or at an in-process fake served over HTTP, is enough to run
+// the pipeline without cloud credentials.
+func NewS3(endpoint, accessKeyID, secretAccessKey, region string, secure bool) (BlobStore, error) {
+	client, err := minio.NewWithRegion(endpoint, accessKeyID, secretAccessKey, secure, region)
+	if err != nil {
+		return nil, err
+	}
+	return &s3Store{client: client}, nil
+}
+
+func (s *s3Store) Get(ctx context.Context, ref gcsref.Ref) (io.ReadCloser, error) {
+	if err := checkScheme(ref, gcsref.SchemeS3); err != nil {
+		return nil, err
+	}
+	obj, err := s.client.GetObjectWithContext(ctx, ref.Bucket, ref.Name, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, s3Error(err)
+	}
+	// GetObject is lazy, so stat the object to surface a missing key here
+	// rather than on the first read.
+	if _, err := obj.Stat(); err != nil {
+		obj.Close()
+		return nil, s3Error(err)
+	}
+	return obj, nil
+}
+
+func (s *s3Store) Put(ctx context.Context, ref gcsref.Ref, r io.Reader, contentType string) error {
+	if err := checkScheme(ref, gcsref.SchemeS3); err != nil {
+		return err
+	}
+	opts := minio.PutObjectOptions{ContentType: contentType}
+	if l, ok := r.(interface {
+		Len() int
+	}); ok {
+		_, err := s.client.PutObjectWithContext(ctx, ref.Bucket, ref.Name, r, int64(l.Len()), opts)
+		return err
+	}
+
+	// Given no length, the client buffers the object in parts of the largest
+	// size it might need, over half a gigabyte each. Spool r to disk instead,
+	// so that the client knows how big the object is.
+	f, err := ioutil.TempFile("", "blobstore-s3-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	size, err := io.Copy(f, r)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	_, err = s.client.PutObjectWithContext(ctx, ref.Bucket, ref.Name, f, size, opts)
+	return err
+}
+
+func (s *s3Store) List(ctx context.Context, prefix gcsref.Ref) ([]Attrs, error) {
+	if err := checkScheme(prefix, gcsref.SchemeS3); err != nil {
+		return nil, err
+	}
+	// Closing done stops the listing, which has no context of its own
+	done := make(chan struct{})
+	defer close(done)
+	objects := s.client.ListObjectsV2(prefix.Bucket, prefix.Name, true, done)
+	// S3 lists keys in lexicographic order already.
+	var list []Attrs
+	for {
+		select {
+		case info, ok := <-objects:
+			if !ok {
+				return list, nil
+			}
+			if info.Err != nil {
+				return nil, info.Err
+			}
+			list = append(list, s3Attrs(prefix.Bucket, info))
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (s *s3Store) Delete(ctx context.Context, ref gcsref.Ref) error {
+	if err := checkScheme(ref, gcsref.SchemeS3); err != nil {
+		return err
+	}
+	// S3 deletes missing keys without complaint, so check for the key first
+	// to report ErrNotExist like the other stores.
+	if _, err := s.Stat(ctx, ref); err != nil {
+		return err
+	}
+	names := make(chan string, 1)
+	names <- ref.Name
+	close(names)
+	var err error
+	for removeErr := range s.client.RemoveObjectsWithContext(ctx, ref.Bucket, names) {
+		if err == nil {
+			err = s3Error(removeErr.Err)
+		}
+	}
+	return err
+}
+
+func (s *s3Store) Stat(ctx context.Context, ref gcsref.Ref) (*Attrs, error) {
+	if err := checkScheme(ref, gcsref.SchemeS3); err != nil {
+		return nil, err
+	}
+	// StatObject has no context, but the Stat of a lazily opened object does
+	obj, err := s.client.GetObjectWithContext(ctx, ref.Bucket, ref.Name, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, s3Error(err)
+	}
+	defer obj.Close()
+	info, err := obj.Stat()
+	if err != nil {
+		return nil, s3Error(err)
+	}
+	a := s3Attrs(ref.Bucket, info)
+	return &a, nil
+}
+
+// Publish returns a presigned URL rather than changing the object's ACL, as
+// many S3-compatible deployments block public ACLs outright.
+func (s *s3Store) Publish(ctx context.Context, ref gcsref.Ref) (string, error) {
+	if err := checkScheme(ref, gcsref.SchemeS3); err != nil {
+		return "", err
+	}
+	u, err := s.client.PresignedGetObject(ref.Bucket, ref.Name, s3PublishExpiry, nil)
+	if err != nil {
+		return "", s3Error(err)
+	}
+	return u.String(), nil
+}
+
+func s3Attrs(bucket string, info minio.ObjectInfo) Attrs {
+	return Attrs{
+		Ref:         gcsref.Ref{Scheme: gcsref.SchemeS3, Bucket: bucket, Name: info.Key},
+		Size:        info.Size,
+		ContentType: info.ContentType,
+		Updated:     info.LastModified,
+		Version:     info.ETag,
+		MD5:         s3MD5(info.ETag),
+	}
+}
+
+// s3MD5 returns the MD5 hash held in an ETag. Objects uploaded in several
+// parts have ETags that are not a hash of their contents, and give nil.
+func s3MD5(etag string) []byte {
+	sum, err := hex.DecodeString(strings.Trim(etag, `"`))
+	if err != nil || len(sum) != md5.Size {
+		return nil
+	}
+	return sum
+}
+
+// s3Error maps a missing key onto ErrNotExist.
+func s3Error(err error) error {
+	if err != nil && minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return ErrNotExist
+	}
+	return err
+}
@@ -0,0 +1,343 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package blobstore
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/gifinator/internal/gcsref"
+	"golang.org/x/net/context"
+)
+
+// fakeS3 is an in-memory stand-in for the parts of the S3 API that s3Store
+// uses. It ignores authentication, and refuses multipart uploads, so that a
+// test fails if the client ever falls back to them.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]fakeObject // by "bucket/name"
+}
+
+type fakeObject struct {
+	data        []byte
+	contentType string
+	modified    time.Time
+}
+
+func (o fakeObject) etag() string {
+	sum := md5.Sum(o.data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	q := r.URL.Query()
+	if _, ok := q["uploads"]; ok {
+		http.Error(w, "multipart uploads are not supported", http.StatusNotImplemented)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if strings.TrimSuffix(path, "/") == strings.SplitN(path, "/", 2)[0] {
+		// A bucket request: only ListObjectsV2 and DeleteObjects are needed
+		bucket := strings.TrimSuffix(path, "/")
+		if _, ok := q["delete"]; ok && r.Method == "POST" {
+			f.deleteObjects(w, r, bucket)
+			return
+		}
+		if q.Get("list-type") != "2" {
+			http.Error(w, "unsupported bucket request", http.StatusNotImplemented)
+			return
+		}
+		f.list(w, bucket, q.Get("prefix"))
+		return
+	}
+
+	switch r.Method {
+	case "PUT":
+		data, err := readS3Body(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		o := fakeObject{data: data, contentType: r.Header.Get("Content-Type"), modified: time.Now()}
+		f.objects[path] = o
+		w.Header().Set("ETag", o.etag())
+	case "GET", "HEAD":
+		o, ok := f.objects[path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			if r.Method == "GET" {
+				io.WriteString(w, `<Error><Code>NoSuchKey</Code><Message>no such key</Message></Error>`)
+			}
+			return
+		}
+		w.Header().Set("ETag", o.etag())
+		w.Header().Set("Content-Type", o.contentType)
+		w.Header().Set("Last-Modified", o.modified.UTC().Format(http.TimeFormat))
+		http.ServeContent(w, r, "", o.modified, bytes.NewReader(o.data))
+	case "DELETE":
+		delete(f.objects, path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "unsupported method", http.StatusMethodNotAllowed)
+	}
+}
+
+// readS3Body reads the object in a PUT request, which the client may send in
+// signed chunks. Either way the request must say how long the object is.
+func readS3Body(r *http.Request) ([]byte, error) {
+	if r.Header.Get("X-Amz-Content-Sha256") != "STREAMING-AWS4-HMAC-SHA256-PAYLOAD" {
+		if r.ContentLength < 0 {
+			return nil, errors.New("missing Content-Length")
+		}
+		return ioutil.ReadAll(r.Body)
+	}
+
+	size, err := strconv.ParseInt(r.Header.Get("X-Amz-Decoded-Content-Length"), 10, 64)
+	if err != nil {
+		return nil, errors.New("missing X-Amz-Decoded-Content-Length")
+	}
+	// Each chunk is "<hex length>;chunk-signature=<sig>\r\n<data>\r\n", and
+	// the last is empty
+	var data []byte
+	br := bufio.NewReader(r.Body)
+	for {
+		header, err := br.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		n, err := strconv.ParseInt(strings.SplitN(header, ";", 2)[0], 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		chunk := make([]byte, n+2)
+		if _, err := io.ReadFull(br, chunk); err != nil {
+			return nil, err
+		}
+		data = append(data, chunk[:n]...)
+		if n == 0 {
+			break
+		}
+	}
+	if int64(len(data)) != size {
+		return nil, fmt.Errorf("got %d bytes, want %d", len(data), size)
+	}
+	return data, nil
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, bucket string, prefix string) {
+	type content struct {
+		Key          string
+		LastModified string
+		ETag         string
+		Size         int64
+	}
+	result := struct {
+		XMLName     xml.Name `xml:"ListBucketResult"`
+		Name        string
+		Prefix      string
+		KeyCount    int
+		IsTruncated bool
+		Contents    []content
+	}{Name: bucket, Prefix: prefix}
+	for path, o := range f.objects {
+		if !strings.HasPrefix(path, bucket+"/"+prefix) {
+			continue
+		}
+		result.Contents = append(result.Contents, content{
+			Key:          strings.TrimPrefix(path, bucket+"/"),
+			LastModified: o.modified.UTC().Format(time.RFC3339),
+			ETag:         o.etag(),
+			Size:         int64(len(o.data)),
+		})
+	}
+	sort.Slice(result.Contents, func(i, j int) bool {
+		return result.Contents[i].Key < result.Contents[j].Key
+	})
+	result.KeyCount = len(result.Contents)
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(result)
+}
+
+func (f *fakeS3) deleteObjects(w http.ResponseWriter, r *http.Request, bucket string) {
+	var req struct {
+		Object []struct{ Key string }
+	}
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	type deleted struct{ Key string }
+	result := struct {
+		XMLName xml.Name `xml:"DeleteResult"`
+		Deleted []deleted
+	}{}
+	for _, o := range req.Object {
+		delete(f.objects, bucket+"/"+o.Key)
+		result.Deleted = append(result.Deleted, deleted{o.Key})
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(result)
+}
+
+func newFakeS3Store(t *testing.T) (BlobStore, func()) {
+	srv := httptest.NewServer(&fakeS3{objects: make(map[string]fakeObject)})
+	s, err := NewS3(strings.TrimPrefix(srv.URL, "http://"), "key", "secret", "us-east-1", false)
+	if err != nil {
+		srv.Close()
+		t.Fatal(err)
+	}
+	return s, srv.Close
+}
+
+func s3Ref(name string) gcsref.Ref {
+	return gcsref.Ref{Scheme: gcsref.SchemeS3, Bucket: "bucket", Name: name}
+}
+
+func TestS3PutGet(t *testing.T) {
+	s, done := newFakeS3Store(t)
+	defer done()
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		r    io.Reader
+		want string
+	}{
+		{"bytes.jpg", bytes.NewReader([]byte("known length")), "known length"},
+		{"tee.png", io.TeeReader(strings.NewReader("unknown length"), ioutil.Discard), "unknown length"},
+		{"empty", io.MultiReader(), ""},
+	}
+	for _, tt := range tests {
+		if err := Upload(ctx, s, s3Ref(tt.name), tt.r, "image/png"); err != nil {
+			t.Errorf("Upload(%s): %v", tt.name, err)
+			continue
+		}
+		attrs, err := s.Stat(ctx, s3Ref(tt.name))
+		if err != nil {
+			t.Errorf("Stat(%s): %v", tt.name, err)
+			continue
+		}
+		if attrs.Size != int64(len(tt.want)) || attrs.MD5 == nil {
+			t.Errorf("Stat(%s) = size %d, MD5 %x; want size %d and an MD5", tt.name, attrs.Size, attrs.MD5, len(tt.want))
+		}
+		var buf bytes.Buffer
+		if err := Download(ctx, s, attrs, &buf); err != nil {
+			t.Errorf("Download(%s): %v", tt.name, err)
+			continue
+		}
+		if buf.String() != tt.want {
+			t.Errorf("Download(%s) = %q, want %q", tt.name, buf.String(), tt.want)
+		}
+	}
+}
+
+func TestS3ListDelete(t *testing.T) {
+	s, done := newFakeS3Store(t)
+	defer done()
+	ctx := context.Background()
+
+	for _, name := range []string{"out.1/frame_0002", "out.1/frame_0000", "out.2/frame_0000", "out.1/frame_0001"} {
+		if err := s.Put(ctx, s3Ref(name), strings.NewReader(name), "image/png"); err != nil {
+			t.Fatalf("Put(%s): %v", name, err)
+		}
+	}
+	list, err := s.List(ctx, s3Ref("out.1/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, attrs := range list {
+		names = append(names, attrs.Ref.Name)
+	}
+	want := "out.1/frame_0000 out.1/frame_0001 out.1/frame_0002"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("List = %s, want %s", got, want)
+	}
+
+	if err := s.Delete(ctx, s3Ref("out.1/frame_0000")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Stat(ctx, s3Ref("out.1/frame_0000")); err != ErrNotExist {
+		t.Errorf("Stat after Delete: got %v, want ErrNotExist", err)
+	}
+	if _, err := s.Get(ctx, s3Ref("out.1/frame_0000")); err != ErrNotExist {
+		t.Errorf("Get after Delete: got %v, want ErrNotExist", err)
+	}
+	if err := s.Delete(ctx, s3Ref("out.1/frame_0000")); err != ErrNotExist {
+		t.Errorf("Delete after Delete: got %v, want ErrNotExist", err)
+	}
+	if _, err := s.Stat(ctx, s3Ref("out.1/frame_0001")); err != nil {
+		t.Errorf("Delete removed more than one object: %v", err)
+	}
+}
+
+func TestS3Cancelled(t *testing.T) {
+	s, done := newFakeS3Store(t)
+	defer done()
+	if err := s.Put(context.Background(), s3Ref("out.1/frame_0000"), strings.NewReader("frame"), "image/png"); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		desc string
+		call func() error
+	}{
+		{"List", func() error { _, err := s.List(ctx, s3Ref("out.1/")); return err }},
+		{"Stat", func() error { _, err := s.Stat(ctx, s3Ref("out.1/frame_0000")); return err }},
+		{"Delete", func() error { return s.Delete(ctx, s3Ref("out.1/frame_0000")) }},
+	}
+	for _, tt := range tests {
+		if err := tt.call(); err == nil {
+			t.Errorf("%s with a cancelled context succeeded", tt.desc)
+		}
+	}
+	if _, err := s.Stat(context.Background(), s3Ref("out.1/frame_0000")); err != nil {
+		t.Errorf("object gone after a cancelled Delete: %v", err)
+	}
+}
+
+func TestS3Publish(t *testing.T) {
+	s, done := newFakeS3Store(t)
+	defer done()
+	u, err := s.Publish(context.Background(), s3Ref("out.1/animated.gif"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(u, "/bucket/out.1/animated.gif?") || !strings.Contains(u, "X-Amz-Signature=") {
+		t.Errorf("Publish = %s, want a presigned URL for the object", u)
+	}
+}
@@ -0,0 +1,211 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package diskcache provides a content-addressed cache of files on local
+// disk, bounded in size and evicting the least recently used files first.
+//
+// Files are stored under a key that names their contents, such as a hash or
+// an object's name and version, so that a key never needs to be invalidated.
+// A file that has been handed out is pinned until it is released, and is
+// never evicted while pinned. A Cache is safe for concurrent use, and
+// concurrent requests for the same missing key only fill it once, unless that
+// fill fails, in which case the next caller in line tries again.
+package diskcache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+// tmpSuffix marks files that are still being written.
+const tmpSuffix = ".tmp-"
+
+// Cache is a size-bounded, content-addressed file cache in one directory.
+type Cache struct {
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex
+	size    int64
+	lru     *list.List // of *entry, most recently used at the front
+	entries map[string]*list.Element
+	filling map[string]*fill
+}
+
+type entry struct {
+	name string
+	size int64
+	pins int
+}
+
+// fill is a key that is being written, which other callers wait on.
+type fill struct {
+	done chan struct{}
+}
+
+// New returns a cache that keeps up to maxBytes of files in dir, creating
+// the directory if needed. Files left in dir by an earlier Cache are kept,
+// and are the first to be evicted, oldest first.
+func New(dir string, maxBytes int64) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	c := &Cache{
+		dir:      dir,
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		entries:  make(map[string]*list.Element),
+		filling:  make(map[string]*fill),
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ModTime().After(infos[j].ModTime())
+	})
+	for _, info := range infos {
+		if !info.Mode().IsRegular() || strings.Contains(info.Name(), tmpSuffix) {
+			os.Remove(filepath.Join(dir, info.Name()))
+			continue
+		}
+		e := &entry{name: info.Name(), size: info.Size()}
+		c.entries[e.name] = c.lru.PushBack(e)
+		c.size += e.size
+	}
+	c.mu.Lock()
+	c.evict()
+	c.mu.Unlock()
+	return c, nil
+}
+
+// Get returns the path of the file stored under key, calling fetch to write
+// its contents if it is not cached yet. The file is pinned until release is
+// called, and must not be modified. If another caller is already filling
+// the key, Get waits for it until ctx is done.
+func (c *Cache) Get(ctx context.Context, key string, fetch func(w io.Writer) error) (path string, release func(), err error) {
+	name := fileName(key)
+	for {
+		c.mu.Lock()
+		if el, ok := c.entries[name]; ok {
+			e := el.Value.(*entry)
+			e.pins++
+			c.lru.MoveToFront(el)
+			c.mu.Unlock()
+			return filepath.Join(c.dir, name), c.releaser(e), nil
+		}
+		if f, ok := c.filling[name]; ok {
+			// Someone else is fetching it; wait for them and look again. If
+			// their fetch failed, perhaps because their caller gave up,
+			// this caller fills it instead.
+			c.mu.Unlock()
+			select {
+			case <-f.done:
+			case <-ctx.Done():
+				return "", nil, ctx.Err()
+			}
+			continue
+		}
+		f := &fill{done: make(chan struct{})}
+		c.filling[name] = f
+		c.mu.Unlock()
+
+		e, err := c.fill(name, fetch)
+
+		c.mu.Lock()
+		delete(c.filling, name)
+		close(f.done)
+		if err != nil {
+			c.mu.Unlock()
+			return "", nil, err
+		}
+		e.pins++
+		c.entries[name] = c.lru.PushFront(e)
+		c.size += e.size
+		c.evict()
+		c.mu.Unlock()
+		return filepath.Join(c.dir, name), c.releaser(e), nil
+	}
+}
+
+// fill writes a new file to a temporary name, and moves it into place once
+// it is complete.
+func (c *Cache) fill(name string, fetch func(w io.Writer) error) (*entry, error) {
+	tmp, err := ioutil.TempFile(c.dir, name+tmpSuffix)
+	if err != nil {
+		return nil, err
+	}
+	err = fetch(tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(c.dir, name))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	info, err := os.Stat(filepath.Join(c.dir, name))
+	if err != nil {
+		return nil, err
+	}
+	return &entry{name: name, size: info.Size()}, nil
+}
+
+func (c *Cache) releaser(e *entry) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mu.Lock()
+			e.pins--
+			c.evict()
+			c.mu.Unlock()
+		})
+	}
+}
+
+// evict removes the least recently used unpinned files until the cache fits
+// in maxBytes. c.mu must be held.
+func (c *Cache) evict() {
+	for el := c.lru.Back(); el != nil && c.size > c.maxBytes; {
+		prev := el.Prev()
+		e := el.Value.(*entry)
+		if e.pins == 0 {
+			os.Remove(filepath.Join(c.dir, e.name))
+			c.lru.Remove(el)
+			delete(c.entries, e.name)
+			c.size -= e.size
+		}
+		el = prev
+	}
+}
+
+// fileName maps a key, which may contain any characters, onto a file name.
+func fileName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,269 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package diskcache
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func newTestCache(t *testing.T, maxBytes int64) (*Cache, string) {
+	dir, err := ioutil.TempDir("", "diskcache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := New(dir, maxBytes)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return c, dir
+}
+
+// contents returns a fetch function that writes s, counting its calls.
+func contents(s string, calls *int) func(w io.Writer) error {
+	return func(w io.Writer) error {
+		*calls++
+		_, err := io.WriteString(w, s)
+		return err
+	}
+}
+
+func TestGetFillsOnce(t *testing.T) {
+	c, dir := newTestCache(t, 100)
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+	calls := 0
+	for i := 0; i < 3; i++ {
+		path, release, err := c.Get(ctx, "gs://bucket/a#1", contents("hello", &calls))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b, err := ioutil.ReadFile(path); err != nil || string(b) != "hello" {
+			t.Errorf("cached file holds %q, %v", b, err)
+		}
+		release()
+		release() // releasing twice is harmless
+	}
+	if calls != 1 {
+		t.Errorf("fetched %d times, want 1", calls)
+	}
+}
+
+func TestEviction(t *testing.T) {
+	ctx := context.Background()
+	// Every file is 10 bytes, and the cache holds 30
+	tests := []struct {
+		desc string
+		// Get each key in turn, keeping it pinned if its name starts with "+"
+		gets []string
+		kept []string
+		gone []string
+	}{
+		{"fits", []string{"a", "b", "c"}, []string{"a", "b", "c"}, nil},
+		{"oldest goes", []string{"a", "b", "c", "d"}, []string{"b", "c", "d"}, []string{"a"}},
+		{"use keeps a file", []string{"a", "b", "c", "a", "d"}, []string{"a", "c", "d"}, []string{"b"}},
+		{"pins keep a file", []string{"+a", "b", "c", "d"}, []string{"a", "c", "d"}, []string{"b"}},
+		{"over size while pinned", []string{"+a", "+b", "+c", "+d"}, []string{"a", "b", "c", "d"}, nil},
+	}
+	for _, tt := range tests {
+		c, dir := newTestCache(t, 30)
+		for _, key := range tt.gets {
+			pin := strings.HasPrefix(key, "+")
+			key = strings.TrimPrefix(key, "+")
+			calls := 0
+			_, release, err := c.Get(ctx, key, contents("0123456789", &calls))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !pin {
+				release()
+			}
+		}
+		for _, key := range tt.kept {
+			if _, err := os.Stat(dir + "/" + fileName(key)); err != nil {
+				t.Errorf("%s: %s evicted", tt.desc, key)
+			}
+		}
+		for _, key := range tt.gone {
+			if _, err := os.Stat(dir + "/" + fileName(key)); err == nil {
+				t.Errorf("%s: %s not evicted", tt.desc, key)
+			}
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+func TestReleaseEvicts(t *testing.T) {
+	c, dir := newTestCache(t, 10)
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+	calls := 0
+	pathA, releaseA, _ := c.Get(ctx, "a", contents("0123456789", &calls))
+	_, releaseB, _ := c.Get(ctx, "b", contents("0123456789", &calls))
+	if _, err := os.Stat(pathA); err != nil {
+		t.Fatalf("pinned file evicted: %v", err)
+	}
+	releaseA()
+	if _, err := os.Stat(pathA); err == nil {
+		t.Errorf("file still cached after release, with the cache over size")
+	}
+	releaseB()
+}
+
+func TestFetchError(t *testing.T) {
+	c, dir := newTestCache(t, 100)
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+	fetchErr := errors.New("no such object")
+	_, _, err := c.Get(ctx, "a", func(w io.Writer) error {
+		io.WriteString(w, "partial")
+		return fetchErr
+	})
+	if err != fetchErr {
+		t.Errorf("Get = %v, want %v", err, fetchErr)
+	}
+	if infos, _ := ioutil.ReadDir(dir); len(infos) != 0 {
+		t.Errorf("failed fill left %d files behind", len(infos))
+	}
+	// The failure is not cached
+	calls := 0
+	if _, _, err := c.Get(ctx, "a", contents("whole", &calls)); err != nil || calls != 1 {
+		t.Errorf("Get after a failure = %v with %d fetches", err, calls)
+	}
+}
+
+func TestConcurrentGet(t *testing.T) {
+	c, dir := newTestCache(t, 100)
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+	var mu sync.Mutex
+	calls := 0
+	start := make(chan struct{})
+	fetch := func(w io.Writer) error {
+		<-start
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		_, err := io.WriteString(w, "hello")
+		return err
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			path, release, err := c.Get(ctx, "a", fetch)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer release()
+			if b, _ := ioutil.ReadFile(path); string(b) != "hello" {
+				t.Errorf("cached file holds %q", b)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+	if calls != 1 {
+		t.Errorf("fetched %d times, want 1", calls)
+	}
+}
+
+func TestNewKeepsFiles(t *testing.T) {
+	c, dir := newTestCache(t, 100)
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+	calls := 0
+	_, release, _ := c.Get(ctx, "a", contents("hello", &calls))
+	release()
+	ioutil.WriteFile(dir+"/"+fileName("b")+tmpSuffix+"1", []byte("half"), 0644)
+
+	c, err := New(dir, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.Get(ctx, "a", contents("hello", &calls)); err != nil || calls != 1 {
+		t.Errorf("Get from a reopened cache = %v with %d fetches, want 1", err, calls)
+	}
+	if infos, _ := ioutil.ReadDir(dir); len(infos) != 1 {
+		t.Errorf("reopened cache holds %d files, want 1", len(infos))
+	}
+}
+
+func TestGetAfterCancelledFill(t *testing.T) {
+	c, dir := newTestCache(t, 100)
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+
+	// The first caller gives up part way through its fetch
+	firstCtx, cancelFirst := context.WithCancel(ctx)
+	started := make(chan struct{})
+	firstErr := make(chan error)
+	go func() {
+		_, _, err := c.Get(firstCtx, "a", func(w io.Writer) error {
+			close(started)
+			<-firstCtx.Done()
+			return firstCtx.Err()
+		})
+		firstErr <- err
+	}()
+	<-started
+
+	// A caller that gives up while waiting gets its own error back
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	calls := 0
+	if _, _, err := c.Get(cancelled, "a", contents("hello", &calls)); err != context.Canceled || calls != 0 {
+		t.Errorf("Get with a cancelled context = %v with %d fetches, want %v", err, calls, context.Canceled)
+	}
+
+	// A caller still waiting fills the key itself
+	type result struct {
+		path string
+		err  error
+	}
+	second := make(chan result)
+	go func() {
+		path, release, err := c.Get(ctx, "a", contents("hello", &calls))
+		if err == nil {
+			release()
+		}
+		second <- result{path, err}
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancelFirst()
+	if err := <-firstErr; err != context.Canceled {
+		t.Errorf("first Get = %v, want %v", err, context.Canceled)
+	}
+	got := <-second
+	if got.err != nil || calls != 1 {
+		t.Fatalf("waiting Get = %v with %d fetches", got.err, calls)
+	}
+	if b, _ := ioutil.ReadFile(got.path); string(b) != "hello" {
+		t.Errorf("cached file holds %q", b)
+	}
+}
@@ -15,7 +15,8 @@
  */
 
 // Package gcsref provides types for referencing Google Cloud Storage
-// buckets and objects.
+// buckets and objects, along with a scheme-neutral Ref type for objects held
+// in other stores.
 package gcsref
 
 import (
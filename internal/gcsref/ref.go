@@ -0,0 +1,112 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gcsref
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Scheme is the URI scheme of a Ref. It names the kind of store that holds
+// the referenced object.
+type Scheme string
+
+// Schemes understood by ParseRef.
+const (
+	SchemeGCS  Scheme = "gs"
+	SchemeS3   Scheme = "s3"
+	SchemeFile Scheme = "file"
+)
+
+// Ref is a scheme-neutral reference to an object in a bucket, written as
+// "<scheme>://<bucket>/<name>". For "file" references the bucket names a
+// directory underneath the root of a local store.
+type Ref struct {
+	Scheme Scheme
+	Bucket string
+	Name   string
+}
+
+// MustParseRef parses a URI into a reference or panics.
+func MustParseRef(uri string) Ref {
+	r, err := ParseRef(uri)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// ParseRef parses a "gs://", "s3://" or "file://" URI into a reference.
+func ParseRef(uri string) (Ref, error) {
+	i := strings.Index(uri, "://")
+	if i == -1 {
+		return Ref{}, fmt.Errorf("parse URI %q: missing scheme", uri)
+	}
+	scheme, rest := Scheme(uri[:i]), uri[i+len("://"):]
+	if !scheme.isKnown() {
+		return Ref{}, fmt.Errorf("parse URI %q: unknown scheme %q", uri, string(scheme))
+	}
+	j := strings.IndexByte(rest, '/')
+	if j == -1 {
+		return Ref{}, fmt.Errorf("parse URI %q: no object name", uri)
+	}
+	r := Ref{Scheme: scheme, Bucket: rest[:j], Name: rest[j+1:]}
+	if !r.IsValid() {
+		return Ref{}, fmt.Errorf("parse URI %q: invalid reference", uri)
+	}
+	return r, nil
+}
+
+// String returns the reference as a URI.
+func (r Ref) String() string {
+	return string(r.Scheme) + "://" + r.Bucket + "/" + r.Name
+}
+
+// Object returns the Cloud Storage object for a "gs" reference.
+func (r Ref) Object() Object {
+	return Bucket(r.Bucket).Object(r.Name)
+}
+
+// IsValid reports whether the reference is well formed for its scheme.
+func (r Ref) IsValid() bool {
+	switch r.Scheme {
+	case SchemeGCS:
+		return r.Object().IsValid()
+	case SchemeS3:
+		// S3 bucket names follow the GCS rules, minus underscores and names
+		// longer than 63 characters.
+		return r.Object().IsValid() && len(r.Bucket) <= 63 && !strings.ContainsRune(r.Bucket, '_')
+	case SchemeFile:
+		if r.Bucket == "" || r.Bucket == "." || r.Bucket == ".." || strings.ContainsAny(r.Bucket, "/\\") {
+			return false
+		}
+		if r.Name == "" || strings.ContainsRune(r.Name, 0) {
+			return false
+		}
+		for _, elem := range strings.Split(r.Name, "/") {
+			if elem == ".." {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func (s Scheme) isKnown() bool {
+	return s == SchemeGCS || s == SchemeS3 || s == SchemeFile
+}
@@ -0,0 +1,53 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gcsref
+
+import "testing"
+
+func TestParseRef(t *testing.T) {
+	tests := []struct {
+		uri  string
+		want Ref
+		ok   bool
+	}{
+		{"gs://bucket/object", Ref{SchemeGCS, "bucket", "object"}, true},
+		{"gs://bucket/dir/object.png", Ref{SchemeGCS, "bucket", "dir/object.png"}, true},
+		{"file://bucket/dir/object.png", Ref{SchemeFile, "bucket", "dir/object.png"}, true},
+		{"bucket/object", Ref{}, false},
+		{"s4://bucket/object", Ref{}, false},
+		{"gs://bucket", Ref{}, false},
+		{"gs://bucket/", Ref{}, false},
+		{"gs://Bucket/object", Ref{}, false},
+		{"file:///object", Ref{}, false},
+		{"file://../object", Ref{}, false},
+		{"file://bucket/../../etc/passwd", Ref{}, false},
+		{"file://bucket/", Ref{}, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseRef(tt.uri)
+		if ok := err == nil; ok != tt.ok {
+			t.Errorf("ParseRef(%q) = %v, want ok %v", tt.uri, err, tt.ok)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseRef(%q) = %+v, want %+v", tt.uri, got, tt.want)
+		}
+		if tt.ok && got.String() != tt.uri {
+			t.Errorf("ParseRef(%q).String() = %q", tt.uri, got.String())
+		}
+	}
+}
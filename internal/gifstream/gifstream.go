@@ -0,0 +1,188 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package gifstream writes animated GIFs one frame at a time.
+//
+// Unlike image/gif's EncodeAll, which needs every frame in memory at once, an
+// Encoder writes each frame out as soon as it is given it, so that only one
+// frame needs to be held at a time. The output loops forever, and each frame
+// carries its own color table.
+package gifstream
+
+import (
+	"bufio"
+	"compress/lzw"
+	"errors"
+	"image"
+	"io"
+)
+
+// Encoder writes the frames of an animated GIF to an io.Writer.
+type Encoder struct {
+	w      *bufio.Writer
+	screen image.Rectangle
+	err    error
+}
+
+// NewEncoder returns an Encoder that writes to w. The size of the animation
+// is taken from its first frame.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: bufio.NewWriter(w)}
+}
+
+// WriteFrame writes a frame that is shown for delay hundredths of a second.
+// Every frame must fit within the bounds of the first.
+func (e *Encoder) WriteFrame(pm *image.Paletted, delay int) error {
+	if e.err != nil {
+		return e.err
+	}
+	b := pm.Bounds()
+	if b.Empty() || b.Max.X >= 1<<16 || b.Max.Y >= 1<<16 {
+		return errors.New("gifstream: frame is empty or too large")
+	}
+	if len(pm.Palette) == 0 || len(pm.Palette) > 256 {
+		return errors.New("gifstream: palette must have between 1 and 256 colors")
+	}
+	if e.screen.Empty() {
+		e.screen = image.Rect(0, 0, b.Max.X, b.Max.Y)
+		e.writeHeader()
+	} else if !b.In(e.screen) {
+		return errors.New("gifstream: frame is outside the animation's bounds")
+	}
+
+	// Graphic control extension, with the delay and any transparent color
+	transparent := -1
+	for i, c := range pm.Palette {
+		if _, _, _, a := c.RGBA(); a == 0 {
+			transparent = i
+			break
+		}
+	}
+	flags := byte(0)
+	if transparent >= 0 {
+		flags |= 0x01
+	} else {
+		transparent = 0
+	}
+	e.write(0x21, 0xf9, 0x04, flags)
+	e.writeUint16(delay)
+	e.write(byte(transparent), 0x00)
+
+	// Image descriptor and local color table
+	bits := 1
+	for 1<<uint(bits) < len(pm.Palette) {
+		bits++
+	}
+	e.write(0x2c)
+	e.writeUint16(b.Min.X)
+	e.writeUint16(b.Min.Y)
+	e.writeUint16(b.Dx())
+	e.writeUint16(b.Dy())
+	e.write(0x80 | byte(bits-1))
+	for i := 0; i < 1<<uint(bits); i++ {
+		if i < len(pm.Palette) {
+			r, g, b, _ := pm.Palette[i].RGBA()
+			e.write(byte(r>>8), byte(g>>8), byte(b>>8))
+		} else {
+			e.write(0, 0, 0)
+		}
+	}
+
+	// Image data, LZW-compressed and split into sub-blocks
+	litWidth := bits
+	if litWidth < 2 {
+		litWidth = 2
+	}
+	e.write(byte(litWidth))
+	bw := &blockWriter{w: e.w}
+	lw := lzw.NewWriter(bw, lzw.LSB, litWidth)
+	for y := b.Min.Y; y < b.Max.Y && e.err == nil; y++ {
+		i := pm.PixOffset(b.Min.X, y)
+		if _, err := lw.Write(pm.Pix[i : i+b.Dx()]); err != nil {
+			e.err = err
+		}
+	}
+	if err := lw.Close(); err != nil && e.err == nil {
+		e.err = err
+	}
+	bw.flush()
+	e.write(0x00)
+	return e.err
+}
+
+// Close ends the animation and flushes it to the underlying writer. It does
+// not close the underlying writer.
+func (e *Encoder) Close() error {
+	if e.err != nil {
+		return e.err
+	}
+	if e.screen.Empty() {
+		return errors.New("gifstream: no frames written")
+	}
+	e.write(0x3b)
+	if err := e.w.Flush(); err != nil {
+		e.err = err
+	}
+	return e.err
+}
+
+// writeHeader writes the logical screen descriptor, without a global color
+// table, and the extension that makes the animation loop forever.
+func (e *Encoder) writeHeader() {
+	io.WriteString(e.w, "GIF89a")
+	e.writeUint16(e.screen.Dx())
+	e.writeUint16(e.screen.Dy())
+	e.write(0x00, 0x00, 0x00)
+	e.write(0x21, 0xff, 0x0b)
+	io.WriteString(e.w, "NETSCAPE2.0")
+	e.write(0x03, 0x01, 0x00, 0x00, 0x00)
+}
+
+func (e *Encoder) write(b ...byte) {
+	e.w.Write(b)
+}
+
+func (e *Encoder) writeUint16(n int) {
+	e.write(byte(n), byte(n>>8))
+}
+
+// blockWriter splits a stream into the length-prefixed sub-blocks of up to
+// 255 bytes that GIF image data is stored in.
+type blockWriter struct {
+	w   *bufio.Writer
+	buf [256]byte
+	n   int
+}
+
+func (bw *blockWriter) Write(p []byte) (int, error) {
+	for _, c := range p {
+		bw.n++
+		bw.buf[bw.n] = c
+		if bw.n == 255 {
+			bw.flush()
+		}
+	}
+	return len(p), nil
+}
+
+func (bw *blockWriter) flush() {
+	if bw.n == 0 {
+		return
+	}
+	bw.buf[0] = byte(bw.n)
+	bw.w.Write(bw.buf[:bw.n+1])
+	bw.n = 0
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gifstream
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+)
+
+func TestEncoderRoundTrip(t *testing.T) {
+	palette := color.Palette{
+		color.RGBA{0x00, 0x00, 0x00, 0xff},
+		color.RGBA{0xff, 0x00, 0x00, 0xff},
+		color.RGBA{0x00, 0xff, 0x00, 0xff},
+		color.RGBA{0x00, 0x00, 0xff, 0xff},
+		color.RGBA{0xff, 0xff, 0xff, 0xff},
+	}
+	tests := []struct {
+		bounds image.Rectangle
+		delay  int
+	}{
+		{image.Rect(0, 0, 40, 30), 0},
+		{image.Rect(0, 0, 40, 30), 10},
+		{image.Rect(5, 5, 25, 20), 250},
+		{image.Rect(0, 0, 40, 30), 1},
+	}
+	var frames []*image.Paletted
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	for i, tt := range tests {
+		pm := image.NewPaletted(tt.bounds, palette)
+		for y := tt.bounds.Min.Y; y < tt.bounds.Max.Y; y++ {
+			for x := tt.bounds.Min.X; x < tt.bounds.Max.X; x++ {
+				pm.SetColorIndex(x, y, uint8((x*7+y*3+i)%len(palette)))
+			}
+		}
+		if err := e.WriteFrame(pm, tt.delay); err != nil {
+			t.Fatalf("WriteFrame(%d) = %v", i, err)
+		}
+		frames = append(frames, pm)
+	}
+	if err := e.Close(); err != nil {
+		t.Fatalf("Close = %v", err)
+	}
+
+	g, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatalf("DecodeAll = %v", err)
+	}
+	if len(g.Image) != len(tests) || len(g.Delay) != len(tests) {
+		t.Fatalf("decoded %d frames and %d delays, want %d", len(g.Image), len(g.Delay), len(tests))
+	}
+	if g.Config.Width != 40 || g.Config.Height != 30 {
+		t.Errorf("animation is %dx%d, want 40x30", g.Config.Width, g.Config.Height)
+	}
+	if g.LoopCount != 0 {
+		t.Errorf("loop count is %d, want 0", g.LoopCount)
+	}
+	for i, tt := range tests {
+		got := g.Image[i]
+		if got.Bounds() != tt.bounds {
+			t.Errorf("frame %d has bounds %v, want %v", i, got.Bounds(), tt.bounds)
+		}
+		if g.Delay[i] != tt.delay {
+			t.Errorf("frame %d has delay %d, want %d", i, g.Delay[i], tt.delay)
+		}
+		for y := tt.bounds.Min.Y; y < tt.bounds.Max.Y; y++ {
+			for x := tt.bounds.Min.X; x < tt.bounds.Max.X; x++ {
+				r1, g1, b1, _ := got.At(x, y).RGBA()
+				r2, g2, b2, _ := frames[i].At(x, y).RGBA()
+				if r1 != r2 || g1 != g2 || b1 != b2 {
+					t.Fatalf("frame %d pixel (%d, %d) is %v, want %v", i, x, y, got.At(x, y), frames[i].At(x, y))
+				}
+			}
+		}
+	}
+}
+
+func TestEncoderErrors(t *testing.T) {
+	palette := color.Palette{color.Black, color.White}
+	tests := []struct {
+		desc   string
+		frames []*image.Paletted
+	}{
+		{"no frames", nil},
+		{"empty frame", []*image.Paletted{image.NewPaletted(image.Rect(0, 0, 0, 0), palette)}},
+		{"no palette", []*image.Paletted{image.NewPaletted(image.Rect(0, 0, 4, 4), nil)}},
+		{"frame outside the first", []*image.Paletted{
+			image.NewPaletted(image.Rect(0, 0, 4, 4), palette),
+			image.NewPaletted(image.Rect(2, 2, 6, 6), palette),
+		}},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		e := NewEncoder(&buf)
+		var err error
+		for _, pm := range tt.frames {
+			if err = e.WriteFrame(pm, 10); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			err = e.Close()
+		}
+		if err == nil {
+			t.Errorf("%s: encoded without error", tt.desc)
+		}
+	}
+}
@@ -0,0 +1,315 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package scene describes the scene that the render service puts a mascot
+// into. Descriptions are JSON documents, so new scenes can be added without
+// rebuilding the render binary.
+package scene
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/fogleman/pt/pt"
+)
+
+// Vector is a point or direction, written as [x, y, z].
+type Vector [3]float64
+
+func (v Vector) pt() pt.Vector {
+	return pt.V(v[0], v[1], v[2])
+}
+
+// Description is a whole scene: the materials it uses, the shapes and lights
+// around the mascot, how to place the mascot's mesh, and where the camera is.
+type Description struct {
+	Materials  map[string]Material `json:"materials"`
+	Primitives []Primitive         `json:"primitives"`
+	Lights     []Light             `json:"lights"`
+	Mesh       Mesh                `json:"mesh"`
+	Camera     Camera              `json:"camera"`
+}
+
+// Material is a surface, referred to by name from primitives and the mesh.
+// Type is one of "diffuse", "specular", "glossy", "clear", "transparent",
+// "metallic" or "light". Colors are written as "#rrggbb", and gloss as an
+// angle in degrees.
+type Material struct {
+	Type      string  `json:"type"`
+	Color     string  `json:"color"`
+	Index     float64 `json:"index"`
+	Gloss     float64 `json:"gloss"`
+	Tint      float64 `json:"tint"`
+	Emittance float64 `json:"emittance"`
+}
+
+// Primitive is a shape in the scene. Type is "cube", which uses Min and Max,
+// or "sphere", which uses Center and Radius.
+type Primitive struct {
+	Type     string  `json:"type"`
+	Min      Vector  `json:"min"`
+	Max      Vector  `json:"max"`
+	Center   Vector  `json:"center"`
+	Radius   float64 `json:"radius"`
+	Material string  `json:"material"`
+}
+
+// Light is a glowing sphere.
+type Light struct {
+	Center    Vector  `json:"center"`
+	Radius    float64 `json:"radius"`
+	Color     string  `json:"color"`
+	Emittance float64 `json:"emittance"`
+}
+
+// Mesh says how to place the mascot. Material is used for any part of the
+// mesh that its .mtl file leaves without a material. The transforms are
+// applied in order, then the mesh is optionally fitted inside a box, and
+// finally turned about the Y axis by the rotation of the frame.
+type Mesh struct {
+	Material      string      `json:"material"`
+	Transforms    []Transform `json:"transforms"`
+	SmoothNormals bool        `json:"smooth_normals"`
+	Fit           *Fit        `json:"fit"`
+}
+
+// Transform is exactly one of a rotation, a translation or a scaling.
+type Transform struct {
+	Rotate    *Rotation `json:"rotate"`
+	Translate *Vector   `json:"translate"`
+	Scale     *Vector   `json:"scale"`
+}
+
+// Rotation turns by Degrees about Axis.
+type Rotation struct {
+	Axis    Vector  `json:"axis"`
+	Degrees float64 `json:"degrees"`
+}
+
+// Fit scales and moves the mesh to fit inside the box from Min to Max, with
+// Anchor saying where in the box it ends up, from [0, 0, 0] to [1, 1, 1].
+type Fit struct {
+	Min    Vector `json:"min"`
+	Max    Vector `json:"max"`
+	Anchor Vector `json:"anchor"`
+}
+
+// Camera looks from Eye towards Center, with a vertical field of view of Fov
+// degrees.
+type Camera struct {
+	Eye    Vector  `json:"eye"`
+	Center Vector  `json:"center"`
+	Up     Vector  `json:"up"`
+	Fov    float64 `json:"fov"`
+}
+
+// Default returns the scene that every mascot was rendered in before scenes
+// could be described: a glossy black mascot in the corner of a cream room,
+// lit from above.
+func Default() *Description {
+	return &Description{
+		Materials: map[string]Material{
+			"object": {Type: "glossy", Color: "#000000", Index: 1.2, Gloss: 30},
+			"wall":   {Type: "glossy", Color: "#fcfae1", Index: 1.5, Gloss: 10},
+		},
+		Primitives: []Primitive{
+			{Type: "cube", Min: Vector{-10, -1, -10}, Max: Vector{-2, 10, 10}, Material: "wall"},
+			{Type: "cube", Min: Vector{-10, -1, -10}, Max: Vector{10, 0, 10}, Material: "wall"},
+		},
+		Lights: []Light{
+			{Center: Vector{4, 10, 1}, Radius: 1, Color: "#ffffff", Emittance: 80},
+		},
+		Mesh: Mesh{
+			Material: "object",
+			Transforms: []Transform{
+				{Rotate: &Rotation{Axis: Vector{0, 1, 0}, Degrees: -10}},
+			},
+			SmoothNormals: true,
+			Fit:           &Fit{Min: Vector{-1, 0, -1}, Max: Vector{1, 2, 1}, Anchor: Vector{0.5, 0, 0.5}},
+		},
+		Camera: Camera{Eye: Vector{4, 1, 0}, Center: Vector{0, 0.9, 0}, Up: Vector{0, 1, 0}, Fov: 30},
+	}
+}
+
+// Parse reads a JSON scene description and checks that it can be built.
+func Parse(r io.Reader) (*Description, error) {
+	var d Description
+	if err := json.NewDecoder(r).Decode(&d); err != nil {
+		return nil, fmt.Errorf("parse scene: %v", err)
+	}
+	if err := d.validate(); err != nil {
+		return nil, fmt.Errorf("parse scene: %v", err)
+	}
+	return &d, nil
+}
+
+func (d *Description) validate() error {
+	for name, m := range d.Materials {
+		if _, err := m.pt(); err != nil {
+			return fmt.Errorf("material %q: %v", name, err)
+		}
+	}
+	for i, p := range d.Primitives {
+		if p.Type != "cube" && p.Type != "sphere" {
+			return fmt.Errorf("primitive %d: unknown type %q", i, p.Type)
+		}
+		if _, ok := d.Materials[p.Material]; !ok {
+			return fmt.Errorf("primitive %d: unknown material %q", i, p.Material)
+		}
+	}
+	for i, l := range d.Lights {
+		if _, err := parseColor(l.Color); err != nil {
+			return fmt.Errorf("light %d: %v", i, err)
+		}
+	}
+	if d.Mesh.Material != "" {
+		if _, ok := d.Materials[d.Mesh.Material]; !ok {
+			return fmt.Errorf("mesh: unknown material %q", d.Mesh.Material)
+		}
+	}
+	for i, t := range d.Mesh.Transforms {
+		n := 0
+		for _, set := range []bool{t.Rotate != nil, t.Translate != nil, t.Scale != nil} {
+			if set {
+				n++
+			}
+		}
+		if n != 1 {
+			return fmt.Errorf("mesh transform %d: need exactly one of rotate, translate or scale", i)
+		}
+	}
+	if d.Camera.Fov <= 0 || d.Camera.Fov >= 180 {
+		return fmt.Errorf("camera: field of view must be between 0 and 180 degrees")
+	}
+	return nil
+}
+
+// Build puts together the scene, loading the mascot's mesh from meshPath and
+// turning it by rotation degrees.
+func (d *Description) Build(meshPath string, rotation float64) (*pt.Scene, *pt.Camera, error) {
+	mesh, err := d.LoadMesh(meshPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return d.BuildWithMesh(mesh, rotation)
+}
+
+// LoadMesh loads the mascot's mesh from meshPath and puts it in place, ready
+// to be turned by BuildWithMesh. Loading it once saves parsing it again for
+// every frame of an animation.
+func (d *Description) LoadMesh(meshPath string) (*pt.Mesh, error) {
+	if err := d.validate(); err != nil {
+		return nil, err
+	}
+	meshMaterial := pt.DiffuseMaterial(pt.White)
+	if d.Mesh.Material != "" {
+		meshMaterial, _ = d.Materials[d.Mesh.Material].pt()
+	}
+	mesh, err := pt.LoadOBJ(meshPath, meshMaterial)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range d.Mesh.Transforms {
+		switch {
+		case t.Rotate != nil:
+			mesh.Transform(pt.Rotate(t.Rotate.Axis.pt(), pt.Radians(t.Rotate.Degrees)))
+		case t.Translate != nil:
+			mesh.Transform(pt.Translate(t.Translate.pt()))
+		case t.Scale != nil:
+			mesh.Transform(pt.Scale(t.Scale.pt()))
+		}
+	}
+	if d.Mesh.SmoothNormals {
+		mesh.SmoothNormals()
+	}
+	if f := d.Mesh.Fit; f != nil {
+		mesh.FitInside(pt.Box{Min: f.Min.pt(), Max: f.Max.pt()}, f.Anchor.pt())
+	}
+	return mesh, nil
+}
+
+// BuildWithMesh puts together the scene around a copy of a mesh from
+// LoadMesh, turned by rotation degrees. The mesh itself is left as it is.
+func (d *Description) BuildWithMesh(mesh *pt.Mesh, rotation float64) (*pt.Scene, *pt.Camera, error) {
+	if err := d.validate(); err != nil {
+		return nil, nil, err
+	}
+	scene := &pt.Scene{}
+	for _, p := range d.Primitives {
+		material, _ := d.Materials[p.Material].pt()
+		switch p.Type {
+		case "cube":
+			scene.Add(pt.NewCube(p.Min.pt(), p.Max.pt(), material))
+		case "sphere":
+			scene.Add(pt.NewSphere(p.Center.pt(), p.Radius, material))
+		}
+	}
+	for _, l := range d.Lights {
+		color, _ := parseColor(l.Color)
+		scene.Add(pt.NewSphere(l.Center.pt(), l.Radius, pt.LightMaterial(color, l.Emittance)))
+	}
+
+	mesh = mesh.Copy()
+	mesh.Transform(pt.Rotate(pt.V(0, 1, 0), pt.Radians(rotation)))
+	scene.Add(mesh)
+
+	c := d.Camera
+	camera := pt.LookAt(c.Eye.pt(), c.Center.pt(), c.Up.pt(), c.Fov)
+	return scene, &camera, nil
+}
+
+func (m Material) pt() (pt.Material, error) {
+	var color pt.Color
+	if m.Type != "clear" {
+		var err error
+		color, err = parseColor(m.Color)
+		if err != nil {
+			return pt.Material{}, err
+		}
+	}
+	switch m.Type {
+	case "diffuse":
+		return pt.DiffuseMaterial(color), nil
+	case "specular":
+		return pt.SpecularMaterial(color, m.Index), nil
+	case "glossy":
+		return pt.GlossyMaterial(color, m.Index, pt.Radians(m.Gloss)), nil
+	case "clear":
+		return pt.ClearMaterial(m.Index, pt.Radians(m.Gloss)), nil
+	case "transparent":
+		return pt.TransparentMaterial(color, m.Index, pt.Radians(m.Gloss), m.Tint), nil
+	case "metallic":
+		return pt.MetallicMaterial(color, pt.Radians(m.Gloss), m.Tint), nil
+	case "light":
+		return pt.LightMaterial(color, m.Emittance), nil
+	}
+	return pt.Material{}, fmt.Errorf("unknown material type %q", m.Type)
+}
+
+// parseColor parses a "#rrggbb" color.
+func parseColor(s string) (pt.Color, error) {
+	if len(s) != 7 || !strings.HasPrefix(s, "#") {
+		return pt.Color{}, fmt.Errorf("color %q is not of the form #rrggbb", s)
+	}
+	x, err := strconv.ParseUint(s[1:], 16, 32)
+	if err != nil {
+		return pt.Color{}, fmt.Errorf("color %q is not of the form #rrggbb", s)
+	}
+	return pt.HexColor(int(x)), nil
+}
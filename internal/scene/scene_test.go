@@ -0,0 +1,81 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scene
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	const camera = `"camera": {"eye": [4, 1, 0], "center": [0, 0, 0], "up": [0, 1, 0], "fov": 30}`
+	tests := []struct {
+		desc  string
+		json  string
+		valid bool
+	}{
+		{"camera only", `{` + camera + `}`, true},
+		{"everything",
+			`{"materials": {"m": {"type": "diffuse", "color": "#ff0000"}, "glass": {"type": "clear", "index": 1.5}},
+			  "primitives": [{"type": "sphere", "center": [0, 1, 0], "radius": 1, "material": "m"},
+			                 {"type": "cube", "min": [0, 0, 0], "max": [1, 1, 1], "material": "glass"}],
+			  "lights": [{"center": [0, 5, 0], "radius": 1, "color": "#FFFFFF", "emittance": 10}],
+			  "mesh": {"material": "m", "transforms": [{"scale": [2, 2, 2]}, {"translate": [0, 1, 0]}]}, ` + camera + `}`, true},
+		{"not JSON", `camera`, false},
+		{"unknown field type", `{"lights": {}, ` + camera + `}`, false},
+		{"no camera", `{}`, false},
+		{"field of view too wide", `{"camera": {"fov": 180}}`, false},
+		{"unknown material type", `{"materials": {"m": {"type": "velvet", "color": "#000000"}}, ` + camera + `}`, false},
+		{"bad color", `{"materials": {"m": {"type": "diffuse", "color": "red"}}, ` + camera + `}`, false},
+		{"short color", `{"materials": {"m": {"type": "diffuse", "color": "#fff"}}, ` + camera + `}`, false},
+		{"unknown primitive", `{"materials": {"m": {"type": "diffuse", "color": "#000000"}},
+			"primitives": [{"type": "torus", "material": "m"}], ` + camera + `}`, false},
+		{"primitive without material", `{"primitives": [{"type": "cube", "material": "m"}], ` + camera + `}`, false},
+		{"bad light color", `{"lights": [{"color": "#gggggg"}], ` + camera + `}`, false},
+		{"mesh without material", `{"mesh": {"material": "m"}, ` + camera + `}`, false},
+		{"empty transform", `{"mesh": {"transforms": [{}]}, ` + camera + `}`, false},
+		{"double transform", `{"mesh": {"transforms": [{"scale": [1, 1, 1], "translate": [0, 0, 0]}]}, ` + camera + `}`, false},
+	}
+	for _, tt := range tests {
+		_, err := Parse(strings.NewReader(tt.json))
+		if valid := err == nil; valid != tt.valid {
+			t.Errorf("%s: Parse = %v, want valid %v", tt.desc, err, tt.valid)
+		}
+	}
+}
+
+func TestDefaultSceneFile(t *testing.T) {
+	// The gifcreator ships the default scene as JSON, which must stay the
+	// same as Default.
+	f, err := os.Open("../../gifcreator/scene/default.scene.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	d, err := Parse(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(d, Default()) {
+		t.Errorf("default.scene.json = %+v, want %+v", d, Default())
+	}
+	if err := Default().validate(); err != nil {
+		t.Errorf("Default is invalid: %v", err)
+	}
+}
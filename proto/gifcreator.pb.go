@@ -6,16 +6,35 @@
 Package renderdemo is a generated protocol buffer package.
 
 It is generated from these files:
+
 	proto/gifcreator.proto
 	proto/render.proto
 
 It has these top-level messages:
+
 	StartJobRequest
 	StartJobResponse
 	GetJobRequest
+	WatchJobRequest
 	GetJobResponse
+	JobProgress
+	JobError
+	CancelJobRequest
+	CancelJobResponse
+	ListJobsRequest
+	ListJobsResponse
+	JobSummary
+	ListScenesRequest
+	ListScenesResponse
+	Scene
 	RenderRequest
+	Region
 	RenderResponse
+	RenderProgress
+	RenderFramesRequest
+	RenderFramesResponse
+	GetLoadRequest
+	RenderLoad
 */
 package renderdemo
 
@@ -39,6 +58,44 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
+// Render quality presets. Each step up costs several times more render time
+// than the one below.
+type Quality int32
+
+const (
+	Quality_UNKNOWN_QUALITY Quality = 0
+	// 150x150, 4 samples per pixel.
+	Quality_DRAFT Quality = 1
+	// 300x300, 16 samples per pixel.
+	Quality_STANDARD Quality = 2
+	// 600x600, 32 samples per pixel, 2 iterations.
+	Quality_HIGH Quality = 3
+	// 2048x2048, 16 samples per pixel, rendered in tiles.
+	Quality_POSTER Quality = 4
+)
+
+var Quality_name = map[int32]string{
+	0: "UNKNOWN_QUALITY",
+	1: "DRAFT",
+	2: "STANDARD",
+	3: "HIGH",
+	4: "POSTER",
+}
+var Quality_value = map[string]int32{
+	"UNKNOWN_QUALITY": 0,
+	"DRAFT":           1,
+	"STANDARD":        2,
+	"HIGH":            3,
+	"POSTER":          4,
+}
+
+func (x Quality) String() string {
+	return proto.EnumName(Quality_name, int32(x))
+}
+func (Quality) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+// The scenes that existed before the scene catalog. GO maps onto the catalog
+// scene "gopher", GRPC onto "grpc" and KUBERNETES onto "k8s".
 type Product int32
 
 const (
@@ -64,7 +121,7 @@ var Product_value = map[string]int32{
 func (x Product) String() string {
 	return proto.EnumName(Product_name, int32(x))
 }
-func (Product) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+func (Product) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
 
 type GetJobResponse_Status int32
 
@@ -73,6 +130,7 @@ const (
 	GetJobResponse_PENDING        GetJobResponse_Status = 1
 	GetJobResponse_DONE           GetJobResponse_Status = 2
 	GetJobResponse_FAILED         GetJobResponse_Status = 3
+	GetJobResponse_CANCELLED      GetJobResponse_Status = 4
 )
 
 var GetJobResponse_Status_name = map[int32]string{
@@ -80,23 +138,114 @@ var GetJobResponse_Status_name = map[int32]string{
 	1: "PENDING",
 	2: "DONE",
 	3: "FAILED",
+	4: "CANCELLED",
 }
 var GetJobResponse_Status_value = map[string]int32{
 	"UNKNOWN_STATUS": 0,
 	"PENDING":        1,
 	"DONE":           2,
 	"FAILED":         3,
+	"CANCELLED":      4,
 }
 
 func (x GetJobResponse_Status) String() string {
 	return proto.EnumName(GetJobResponse_Status_name, int32(x))
 }
-func (GetJobResponse_Status) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{3, 0} }
+func (GetJobResponse_Status) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{4, 0} }
+
+// The step of the pipeline the job is in.
+type JobProgress_Stage int32
+
+const (
+	JobProgress_UNKNOWN_STAGE    JobProgress_Stage = 0
+	JobProgress_PREPARING_ASSETS JobProgress_Stage = 1
+	JobProgress_RENDERING        JobProgress_Stage = 2
+	JobProgress_COMPOSITING      JobProgress_Stage = 3
+	JobProgress_UPLOADING        JobProgress_Stage = 4
+)
+
+var JobProgress_Stage_name = map[int32]string{
+	0: "UNKNOWN_STAGE",
+	1: "PREPARING_ASSETS",
+	2: "RENDERING",
+	3: "COMPOSITING",
+	4: "UPLOADING",
+}
+var JobProgress_Stage_value = map[string]int32{
+	"UNKNOWN_STAGE":    0,
+	"PREPARING_ASSETS": 1,
+	"RENDERING":        2,
+	"COMPOSITING":      3,
+	"UPLOADING":        4,
+}
+
+func (x JobProgress_Stage) String() string {
+	return proto.EnumName(JobProgress_Stage_name, int32(x))
+}
+func (JobProgress_Stage) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{5, 0} }
+
+// The step of the pipeline that failed.
+type JobError_Stage int32
+
+const (
+	JobError_UNKNOWN_STAGE       JobError_Stage = 0
+	JobError_TRANSFORM_TEMPLATES JobError_Stage = 1
+	JobError_UPLOAD_ASSETS       JobError_Stage = 2
+	JobError_UPLOAD_BADGE        JobError_Stage = 3
+	JobError_RENDER_FRAME        JobError_Stage = 4
+	JobError_COMPILE_GIF         JobError_Stage = 5
+	JobError_STITCH_TILES        JobError_Stage = 6
+)
+
+var JobError_Stage_name = map[int32]string{
+	0: "UNKNOWN_STAGE",
+	1: "TRANSFORM_TEMPLATES",
+	2: "UPLOAD_ASSETS",
+	3: "UPLOAD_BADGE",
+	4: "RENDER_FRAME",
+	5: "COMPILE_GIF",
+	6: "STITCH_TILES",
+}
+var JobError_Stage_value = map[string]int32{
+	"UNKNOWN_STAGE":       0,
+	"TRANSFORM_TEMPLATES": 1,
+	"UPLOAD_ASSETS":       2,
+	"UPLOAD_BADGE":        3,
+	"RENDER_FRAME":        4,
+	"COMPILE_GIF":         5,
+	"STITCH_TILES":        6,
+}
+
+func (x JobError_Stage) String() string {
+	return proto.EnumName(JobError_Stage_name, int32(x))
+}
+func (JobError_Stage) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{6, 0} }
 
 type StartJobRequest struct {
 	// TODO(light): what scene parameters do we want to give?
-	Name          string  `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	// Deprecated: set scene_id instead. Used only if scene_id is empty.
 	ProductToPlug Product `protobuf:"varint,2,opt,name=product_to_plug,json=productToPlug,enum=renderdemo.Product" json:"product_to_plug,omitempty"`
+	// How many frames to render. Defaults to 15.
+	FrameCount int32 `protobuf:"varint,3,opt,name=frame_count,json=frameCount" json:"frame_count,omitempty"`
+	// The rotation of the mascot, in degrees, in the first and last frames.
+	// The frames in between are spread evenly. A start and end angle of 0 and
+	// 0 are read as unset, and become 20 and 48 degrees; to hold the mascot
+	// still facing forwards, set both to 360.
+	StartAngle float32 `protobuf:"fixed32,4,opt,name=start_angle,json=startAngle" json:"start_angle,omitempty"`
+	EndAngle   float32 `protobuf:"fixed32,5,opt,name=end_angle,json=endAngle" json:"end_angle,omitempty"`
+	// The playback speed of the GIF. If it is 0, the frames have no delay
+	// between them, as before this field existed, and viewers show them as fast
+	// as they choose.
+	FramesPerSecond int32 `protobuf:"varint,6,opt,name=frames_per_second,json=framesPerSecond" json:"frames_per_second,omitempty"`
+	// How big and how clean the frames are. Defaults to STANDARD.
+	Quality Quality `protobuf:"varint,7,opt,name=quality,enum=renderdemo.Quality" json:"quality,omitempty"`
+	// The scene to render, from ListScenes.
+	SceneId string `protobuf:"bytes,8,opt,name=scene_id,json=sceneId" json:"scene_id,omitempty"`
+	// Also render a quick, low-resolution draft of the GIF. Its frames are
+	// rendered ahead of every other job's, and GetJob reports it as
+	// preview_image_url while the full-quality GIF renders.
+	Preview bool `protobuf:"varint,9,opt,name=preview" json:"preview,omitempty"`
 }
 
 func (m *StartJobRequest) Reset()                    { *m = StartJobRequest{} }
@@ -118,8 +267,61 @@ func (m *StartJobRequest) GetProductToPlug() Product {
 	return Product_UNKNOWN_PRODUCT
 }
 
+func (m *StartJobRequest) GetFrameCount() int32 {
+	if m != nil {
+		return m.FrameCount
+	}
+	return 0
+}
+
+func (m *StartJobRequest) GetStartAngle() float32 {
+	if m != nil {
+		return m.StartAngle
+	}
+	return 0
+}
+
+func (m *StartJobRequest) GetEndAngle() float32 {
+	if m != nil {
+		return m.EndAngle
+	}
+	return 0
+}
+
+func (m *StartJobRequest) GetFramesPerSecond() int32 {
+	if m != nil {
+		return m.FramesPerSecond
+	}
+	return 0
+}
+
+func (m *StartJobRequest) GetQuality() Quality {
+	if m != nil {
+		return m.Quality
+	}
+	return Quality_UNKNOWN_QUALITY
+}
+
+func (m *StartJobRequest) GetSceneId() string {
+	if m != nil {
+		return m.SceneId
+	}
+	return ""
+}
+
+func (m *StartJobRequest) GetPreview() bool {
+	if m != nil {
+		return m.Preview
+	}
+	return false
+}
+
 type StartJobResponse struct {
+	// An opaque, unguessable ID for the job.
 	JobId string `protobuf:"bytes,1,opt,name=job_id,json=jobId" json:"job_id,omitempty"`
+	// A secret that proves ownership of the job. Calls that change the job,
+	// such as CancelJob, must present it.
+	OwnerToken string `protobuf:"bytes,2,opt,name=owner_token,json=ownerToken" json:"owner_token,omitempty"`
 }
 
 func (m *StartJobResponse) Reset()                    { *m = StartJobResponse{} }
@@ -134,6 +336,13 @@ func (m *StartJobResponse) GetJobId() string {
 	return ""
 }
 
+func (m *StartJobResponse) GetOwnerToken() string {
+	if m != nil {
+		return m.OwnerToken
+	}
+	return ""
+}
+
 type GetJobRequest struct {
 	JobId string `protobuf:"bytes,1,opt,name=job_id,json=jobId" json:"job_id,omitempty"`
 }
@@ -150,16 +359,42 @@ func (m *GetJobRequest) GetJobId() string {
 	return ""
 }
 
+type WatchJobRequest struct {
+	JobId string `protobuf:"bytes,1,opt,name=job_id,json=jobId" json:"job_id,omitempty"`
+}
+
+func (m *WatchJobRequest) Reset()                    { *m = WatchJobRequest{} }
+func (m *WatchJobRequest) String() string            { return proto.CompactTextString(m) }
+func (*WatchJobRequest) ProtoMessage()               {}
+func (*WatchJobRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+func (m *WatchJobRequest) GetJobId() string {
+	if m != nil {
+		return m.JobId
+	}
+	return ""
+}
+
 type GetJobResponse struct {
 	Status GetJobResponse_Status `protobuf:"varint,1,opt,name=status,enum=renderdemo.GetJobResponse_Status" json:"status,omitempty"`
-	// World-readable URL for created image.
+	// World-readable URL for created image. Only set when status is DONE.
 	ImageUrl string `protobuf:"bytes,2,opt,name=image_url,json=imageUrl" json:"image_url,omitempty"`
+	// Why the job failed. Only set when status is FAILED.
+	Error *JobError `protobuf:"bytes,3,opt,name=error" json:"error,omitempty"`
+	// How far along the job is.
+	Progress *JobProgress `protobuf:"bytes,4,opt,name=progress" json:"progress,omitempty"`
+	// World-readable URL for a rough version of the image, to show while the
+	// job renders. It is the draft for jobs started with preview set, and
+	// otherwise is put together from early previews of each frame, for
+	// qualities that take several iterations. Set once every frame has a
+	// preview, and kept once the job is DONE.
+	PreviewImageUrl string `protobuf:"bytes,5,opt,name=preview_image_url,json=previewImageUrl" json:"preview_image_url,omitempty"`
 }
 
 func (m *GetJobResponse) Reset()                    { *m = GetJobResponse{} }
 func (m *GetJobResponse) String() string            { return proto.CompactTextString(m) }
 func (*GetJobResponse) ProtoMessage()               {}
-func (*GetJobResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+func (*GetJobResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
 
 func (m *GetJobResponse) GetStatus() GetJobResponse_Status {
 	if m != nil {
@@ -175,13 +410,371 @@ func (m *GetJobResponse) GetImageUrl() string {
 	return ""
 }
 
+func (m *GetJobResponse) GetError() *JobError {
+	if m != nil {
+		return m.Error
+	}
+	return nil
+}
+
+func (m *GetJobResponse) GetProgress() *JobProgress {
+	if m != nil {
+		return m.Progress
+	}
+	return nil
+}
+
+func (m *GetJobResponse) GetPreviewImageUrl() string {
+	if m != nil {
+		return m.PreviewImageUrl
+	}
+	return ""
+}
+
+type JobProgress struct {
+	Stage           JobProgress_Stage `protobuf:"varint,1,opt,name=stage,enum=renderdemo.JobProgress_Stage" json:"stage,omitempty"`
+	FramesCompleted int64             `protobuf:"varint,2,opt,name=frames_completed,json=framesCompleted" json:"frames_completed,omitempty"`
+	FramesTotal     int64             `protobuf:"varint,3,opt,name=frames_total,json=framesTotal" json:"frames_total,omitempty"`
+	// Unix times, in seconds, of when the job was started, when it last moved
+	// forward, and when it finished. end_time is 0 while the job is PENDING.
+	StartTime  int64 `protobuf:"varint,4,opt,name=start_time,json=startTime" json:"start_time,omitempty"`
+	UpdateTime int64 `protobuf:"varint,5,opt,name=update_time,json=updateTime" json:"update_time,omitempty"`
+	EndTime    int64 `protobuf:"varint,6,opt,name=end_time,json=endTime" json:"end_time,omitempty"`
+}
+
+func (m *JobProgress) Reset()                    { *m = JobProgress{} }
+func (m *JobProgress) String() string            { return proto.CompactTextString(m) }
+func (*JobProgress) ProtoMessage()               {}
+func (*JobProgress) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *JobProgress) GetStage() JobProgress_Stage {
+	if m != nil {
+		return m.Stage
+	}
+	return JobProgress_UNKNOWN_STAGE
+}
+
+func (m *JobProgress) GetFramesCompleted() int64 {
+	if m != nil {
+		return m.FramesCompleted
+	}
+	return 0
+}
+
+func (m *JobProgress) GetFramesTotal() int64 {
+	if m != nil {
+		return m.FramesTotal
+	}
+	return 0
+}
+
+func (m *JobProgress) GetStartTime() int64 {
+	if m != nil {
+		return m.StartTime
+	}
+	return 0
+}
+
+func (m *JobProgress) GetUpdateTime() int64 {
+	if m != nil {
+		return m.UpdateTime
+	}
+	return 0
+}
+
+func (m *JobProgress) GetEndTime() int64 {
+	if m != nil {
+		return m.EndTime
+	}
+	return 0
+}
+
+type JobError struct {
+	Stage JobError_Stage `protobuf:"varint,1,opt,name=stage,enum=renderdemo.JobError_Stage" json:"stage,omitempty"`
+	// The frame that could not be rendered, if stage is RENDER_FRAME or
+	// STITCH_TILES.
+	Frame   int64  `protobuf:"varint,2,opt,name=frame" json:"frame,omitempty"`
+	Message string `protobuf:"bytes,3,opt,name=message" json:"message,omitempty"`
+}
+
+func (m *JobError) Reset()                    { *m = JobError{} }
+func (m *JobError) String() string            { return proto.CompactTextString(m) }
+func (*JobError) ProtoMessage()               {}
+func (*JobError) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+func (m *JobError) GetStage() JobError_Stage {
+	if m != nil {
+		return m.Stage
+	}
+	return JobError_UNKNOWN_STAGE
+}
+
+func (m *JobError) GetFrame() int64 {
+	if m != nil {
+		return m.Frame
+	}
+	return 0
+}
+
+func (m *JobError) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
+type CancelJobRequest struct {
+	JobId string `protobuf:"bytes,1,opt,name=job_id,json=jobId" json:"job_id,omitempty"`
+	// The owner_token returned by StartJob for the job. Jobs started before
+	// owner tokens existed take the gifcreator server's OPERATOR_TOKEN instead.
+	OwnerToken string `protobuf:"bytes,2,opt,name=owner_token,json=ownerToken" json:"owner_token,omitempty"`
+}
+
+func (m *CancelJobRequest) Reset()                    { *m = CancelJobRequest{} }
+func (m *CancelJobRequest) String() string            { return proto.CompactTextString(m) }
+func (*CancelJobRequest) ProtoMessage()               {}
+func (*CancelJobRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+func (m *CancelJobRequest) GetJobId() string {
+	if m != nil {
+		return m.JobId
+	}
+	return ""
+}
+
+func (m *CancelJobRequest) GetOwnerToken() string {
+	if m != nil {
+		return m.OwnerToken
+	}
+	return ""
+}
+
+type CancelJobResponse struct {
+}
+
+func (m *CancelJobResponse) Reset()                    { *m = CancelJobResponse{} }
+func (m *CancelJobResponse) String() string            { return proto.CompactTextString(m) }
+func (*CancelJobResponse) ProtoMessage()               {}
+func (*CancelJobResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+type ListJobsRequest struct {
+	// The gifcreator server's OPERATOR_TOKEN. Listing jobs reveals their IDs,
+	// so only operators may do it.
+	OperatorToken string `protobuf:"bytes,6,opt,name=operator_token,json=operatorToken" json:"operator_token,omitempty"`
+	// Only list jobs with this status, unless it is UNKNOWN_STATUS.
+	Status GetJobResponse_Status `protobuf:"varint,1,opt,name=status,enum=renderdemo.GetJobResponse_Status" json:"status,omitempty"`
+	// Only list jobs for this product, unless it is UNKNOWN_PRODUCT.
+	Product Product `protobuf:"varint,2,opt,name=product,enum=renderdemo.Product" json:"product,omitempty"`
+	// Only list jobs for this scene, unless it is empty. Takes precedence over
+	// product.
+	SceneId string `protobuf:"bytes,5,opt,name=scene_id,json=sceneId" json:"scene_id,omitempty"`
+	// The most jobs to return. Defaults to 20, and is capped at 100.
+	PageSize int32 `protobuf:"varint,3,opt,name=page_size,json=pageSize" json:"page_size,omitempty"`
+	// The next_page_token of a previous ListJobs call with the same filters,
+	// to carry on from where it left off.
+	PageToken string `protobuf:"bytes,4,opt,name=page_token,json=pageToken" json:"page_token,omitempty"`
+}
+
+func (m *ListJobsRequest) Reset()                    { *m = ListJobsRequest{} }
+func (m *ListJobsRequest) String() string            { return proto.CompactTextString(m) }
+func (*ListJobsRequest) ProtoMessage()               {}
+func (*ListJobsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+func (m *ListJobsRequest) GetOperatorToken() string {
+	if m != nil {
+		return m.OperatorToken
+	}
+	return ""
+}
+
+func (m *ListJobsRequest) GetStatus() GetJobResponse_Status {
+	if m != nil {
+		return m.Status
+	}
+	return GetJobResponse_UNKNOWN_STATUS
+}
+
+func (m *ListJobsRequest) GetProduct() Product {
+	if m != nil {
+		return m.Product
+	}
+	return Product_UNKNOWN_PRODUCT
+}
+
+func (m *ListJobsRequest) GetSceneId() string {
+	if m != nil {
+		return m.SceneId
+	}
+	return ""
+}
+
+func (m *ListJobsRequest) GetPageSize() int32 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
+}
+
+func (m *ListJobsRequest) GetPageToken() string {
+	if m != nil {
+		return m.PageToken
+	}
+	return ""
+}
+
+type ListJobsResponse struct {
+	Jobs []*JobSummary `protobuf:"bytes,1,rep,name=jobs" json:"jobs,omitempty"`
+	// Pass as page_token to get the next page. Empty on the last page.
+	NextPageToken string `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken" json:"next_page_token,omitempty"`
+}
+
+func (m *ListJobsResponse) Reset()                    { *m = ListJobsResponse{} }
+func (m *ListJobsResponse) String() string            { return proto.CompactTextString(m) }
+func (*ListJobsResponse) ProtoMessage()               {}
+func (*ListJobsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+func (m *ListJobsResponse) GetJobs() []*JobSummary {
+	if m != nil {
+		return m.Jobs
+	}
+	return nil
+}
+
+func (m *ListJobsResponse) GetNextPageToken() string {
+	if m != nil {
+		return m.NextPageToken
+	}
+	return ""
+}
+
+type JobSummary struct {
+	JobId   string                `protobuf:"bytes,1,opt,name=job_id,json=jobId" json:"job_id,omitempty"`
+	Status  GetJobResponse_Status `protobuf:"varint,2,opt,name=status,enum=renderdemo.GetJobResponse_Status" json:"status,omitempty"`
+	Product Product               `protobuf:"varint,3,opt,name=product,enum=renderdemo.Product" json:"product,omitempty"`
+	Caption string                `protobuf:"bytes,4,opt,name=caption" json:"caption,omitempty"`
+	// Unix time, in seconds, of when the job was started.
+	CreateTime int64  `protobuf:"varint,5,opt,name=create_time,json=createTime" json:"create_time,omitempty"`
+	SceneId    string `protobuf:"bytes,6,opt,name=scene_id,json=sceneId" json:"scene_id,omitempty"`
+}
+
+func (m *JobSummary) Reset()                    { *m = JobSummary{} }
+func (m *JobSummary) String() string            { return proto.CompactTextString(m) }
+func (*JobSummary) ProtoMessage()               {}
+func (*JobSummary) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+func (m *JobSummary) GetJobId() string {
+	if m != nil {
+		return m.JobId
+	}
+	return ""
+}
+
+func (m *JobSummary) GetStatus() GetJobResponse_Status {
+	if m != nil {
+		return m.Status
+	}
+	return GetJobResponse_UNKNOWN_STATUS
+}
+
+func (m *JobSummary) GetProduct() Product {
+	if m != nil {
+		return m.Product
+	}
+	return Product_UNKNOWN_PRODUCT
+}
+
+func (m *JobSummary) GetCaption() string {
+	if m != nil {
+		return m.Caption
+	}
+	return ""
+}
+
+func (m *JobSummary) GetCreateTime() int64 {
+	if m != nil {
+		return m.CreateTime
+	}
+	return 0
+}
+
+func (m *JobSummary) GetSceneId() string {
+	if m != nil {
+		return m.SceneId
+	}
+	return ""
+}
+
+type ListScenesRequest struct {
+}
+
+func (m *ListScenesRequest) Reset()                    { *m = ListScenesRequest{} }
+func (m *ListScenesRequest) String() string            { return proto.CompactTextString(m) }
+func (*ListScenesRequest) ProtoMessage()               {}
+func (*ListScenesRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+type ListScenesResponse struct {
+	Scenes []*Scene `protobuf:"bytes,1,rep,name=scenes" json:"scenes,omitempty"`
+}
+
+func (m *ListScenesResponse) Reset()                    { *m = ListScenesResponse{} }
+func (m *ListScenesResponse) String() string            { return proto.CompactTextString(m) }
+func (*ListScenesResponse) ProtoMessage()               {}
+func (*ListScenesResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+func (m *ListScenesResponse) GetScenes() []*Scene {
+	if m != nil {
+		return m.Scenes
+	}
+	return nil
+}
+
+type Scene struct {
+	// The ID to pass as scene_id to StartJob.
+	Id string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	// A name for the scene fit to show users.
+	DisplayName string `protobuf:"bytes,2,opt,name=display_name,json=displayName" json:"display_name,omitempty"`
+}
+
+func (m *Scene) Reset()                    { *m = Scene{} }
+func (m *Scene) String() string            { return proto.CompactTextString(m) }
+func (*Scene) ProtoMessage()               {}
+func (*Scene) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+
+func (m *Scene) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Scene) GetDisplayName() string {
+	if m != nil {
+		return m.DisplayName
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*StartJobRequest)(nil), "renderdemo.StartJobRequest")
 	proto.RegisterType((*StartJobResponse)(nil), "renderdemo.StartJobResponse")
 	proto.RegisterType((*GetJobRequest)(nil), "renderdemo.GetJobRequest")
+	proto.RegisterType((*WatchJobRequest)(nil), "renderdemo.WatchJobRequest")
 	proto.RegisterType((*GetJobResponse)(nil), "renderdemo.GetJobResponse")
+	proto.RegisterType((*JobProgress)(nil), "renderdemo.JobProgress")
+	proto.RegisterType((*JobError)(nil), "renderdemo.JobError")
+	proto.RegisterType((*CancelJobRequest)(nil), "renderdemo.CancelJobRequest")
+	proto.RegisterType((*CancelJobResponse)(nil), "renderdemo.CancelJobResponse")
+	proto.RegisterType((*ListJobsRequest)(nil), "renderdemo.ListJobsRequest")
+	proto.RegisterType((*ListJobsResponse)(nil), "renderdemo.ListJobsResponse")
+	proto.RegisterType((*JobSummary)(nil), "renderdemo.JobSummary")
+	proto.RegisterType((*ListScenesRequest)(nil), "renderdemo.ListScenesRequest")
+	proto.RegisterType((*ListScenesResponse)(nil), "renderdemo.ListScenesResponse")
+	proto.RegisterType((*Scene)(nil), "renderdemo.Scene")
+	proto.RegisterEnum("renderdemo.Quality", Quality_name, Quality_value)
 	proto.RegisterEnum("renderdemo.Product", Product_name, Product_value)
 	proto.RegisterEnum("renderdemo.GetJobResponse_Status", GetJobResponse_Status_name, GetJobResponse_Status_value)
+	proto.RegisterEnum("renderdemo.JobProgress_Stage", JobProgress_Stage_name, JobProgress_Stage_value)
+	proto.RegisterEnum("renderdemo.JobError_Stage", JobError_Stage_name, JobError_Stage_value)
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -197,6 +790,10 @@ const _ = grpc.SupportPackageIsVersion4
 type GifCreatorClient interface {
 	StartJob(ctx context.Context, in *StartJobRequest, opts ...grpc.CallOption) (*StartJobResponse, error)
 	GetJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*GetJobResponse, error)
+	WatchJob(ctx context.Context, in *WatchJobRequest, opts ...grpc.CallOption) (GifCreator_WatchJobClient, error)
+	CancelJob(ctx context.Context, in *CancelJobRequest, opts ...grpc.CallOption) (*CancelJobResponse, error)
+	ListJobs(ctx context.Context, in *ListJobsRequest, opts ...grpc.CallOption) (*ListJobsResponse, error)
+	ListScenes(ctx context.Context, in *ListScenesRequest, opts ...grpc.CallOption) (*ListScenesResponse, error)
 }
 
 type gifCreatorClient struct {
@@ -225,11 +822,74 @@ func (c *gifCreatorClient) GetJob(ctx context.Context, in *GetJobRequest, opts .
 	return out, nil
 }
 
+func (c *gifCreatorClient) WatchJob(ctx context.Context, in *WatchJobRequest, opts ...grpc.CallOption) (GifCreator_WatchJobClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_GifCreator_serviceDesc.Streams[0], c.cc, "/renderdemo.GifCreator/WatchJob", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &gifCreatorWatchJobClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type GifCreator_WatchJobClient interface {
+	Recv() (*GetJobResponse, error)
+	grpc.ClientStream
+}
+
+type gifCreatorWatchJobClient struct {
+	grpc.ClientStream
+}
+
+func (x *gifCreatorWatchJobClient) Recv() (*GetJobResponse, error) {
+	m := new(GetJobResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *gifCreatorClient) CancelJob(ctx context.Context, in *CancelJobRequest, opts ...grpc.CallOption) (*CancelJobResponse, error) {
+	out := new(CancelJobResponse)
+	err := grpc.Invoke(ctx, "/renderdemo.GifCreator/CancelJob", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gifCreatorClient) ListJobs(ctx context.Context, in *ListJobsRequest, opts ...grpc.CallOption) (*ListJobsResponse, error) {
+	out := new(ListJobsResponse)
+	err := grpc.Invoke(ctx, "/renderdemo.GifCreator/ListJobs", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gifCreatorClient) ListScenes(ctx context.Context, in *ListScenesRequest, opts ...grpc.CallOption) (*ListScenesResponse, error) {
+	out := new(ListScenesResponse)
+	err := grpc.Invoke(ctx, "/renderdemo.GifCreator/ListScenes", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for GifCreator service
 
 type GifCreatorServer interface {
 	StartJob(context.Context, *StartJobRequest) (*StartJobResponse, error)
 	GetJob(context.Context, *GetJobRequest) (*GetJobResponse, error)
+	WatchJob(*WatchJobRequest, GifCreator_WatchJobServer) error
+	CancelJob(context.Context, *CancelJobRequest) (*CancelJobResponse, error)
+	ListJobs(context.Context, *ListJobsRequest) (*ListJobsResponse, error)
+	ListScenes(context.Context, *ListScenesRequest) (*ListScenesResponse, error)
 }
 
 func RegisterGifCreatorServer(s *grpc.Server, srv GifCreatorServer) {
@@ -272,6 +932,81 @@ func _GifCreator_GetJob_Handler(srv interface{}, ctx context.Context, dec func(i
 	return interceptor(ctx, in, info, handler)
 }
 
+func _GifCreator_WatchJob_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchJobRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GifCreatorServer).WatchJob(m, &gifCreatorWatchJobServer{stream})
+}
+
+type GifCreator_WatchJobServer interface {
+	Send(*GetJobResponse) error
+	grpc.ServerStream
+}
+
+type gifCreatorWatchJobServer struct {
+	grpc.ServerStream
+}
+
+func (x *gifCreatorWatchJobServer) Send(m *GetJobResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _GifCreator_CancelJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GifCreatorServer).CancelJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/renderdemo.GifCreator/CancelJob",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GifCreatorServer).CancelJob(ctx, req.(*CancelJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GifCreator_ListJobs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListJobsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GifCreatorServer).ListJobs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/renderdemo.GifCreator/ListJobs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GifCreatorServer).ListJobs(ctx, req.(*ListJobsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GifCreator_ListScenes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListScenesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GifCreatorServer).ListScenes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/renderdemo.GifCreator/ListScenes",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GifCreatorServer).ListScenes(ctx, req.(*ListScenesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _GifCreator_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "renderdemo.GifCreator",
 	HandlerType: (*GifCreatorServer)(nil),
@@ -284,38 +1019,111 @@ var _GifCreator_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetJob",
 			Handler:    _GifCreator_GetJob_Handler,
 		},
+		{
+			MethodName: "CancelJob",
+			Handler:    _GifCreator_CancelJob_Handler,
+		},
+		{
+			MethodName: "ListJobs",
+			Handler:    _GifCreator_ListJobs_Handler,
+		},
+		{
+			MethodName: "ListScenes",
+			Handler:    _GifCreator_ListScenes_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchJob",
+			Handler:       _GifCreator_WatchJob_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/gifcreator.proto",
 }
 
 func init() { proto.RegisterFile("proto/gifcreator.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 389 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x74, 0x92, 0x51, 0x8f, 0x93, 0x40,
-	0x10, 0xc7, 0x0f, 0xee, 0xe4, 0xda, 0xd1, 0xa3, 0x9b, 0xb9, 0x68, 0xce, 0x3b, 0x1f, 0x4e, 0x1e,
-	0xcc, 0xe9, 0x03, 0x26, 0xf5, 0xc9, 0xf8, 0x70, 0xde, 0x15, 0x24, 0xb5, 0x06, 0xc8, 0x02, 0x31,
-	0xf1, 0x85, 0x40, 0xd9, 0x12, 0x1a, 0xda, 0xc5, 0x65, 0xf9, 0x26, 0x7e, 0x09, 0xbf, 0xa5, 0x11,
-	0x68, 0x8a, 0xa6, 0x7d, 0xdb, 0xcc, 0xfe, 0xe6, 0x3f, 0x33, 0xff, 0x19, 0x78, 0x51, 0x09, 0x2e,
-	0xf9, 0xfb, 0xbc, 0x58, 0x2d, 0x05, 0x4b, 0x24, 0x17, 0x66, 0x1b, 0x40, 0x10, 0x6c, 0x9b, 0x31,
-	0x91, 0xb1, 0x0d, 0x37, 0x52, 0x98, 0x04, 0x32, 0x11, 0xf2, 0x2b, 0x4f, 0x29, 0xfb, 0xd9, 0xb0,
-	0x5a, 0x22, 0xc2, 0xd9, 0x36, 0xd9, 0xb0, 0x2b, 0xe5, 0x56, 0xb9, 0x1b, 0xd3, 0xf6, 0x8d, 0x9f,
-	0x60, 0x52, 0x09, 0x9e, 0x35, 0x4b, 0x19, 0x4b, 0x1e, 0x57, 0x65, 0x93, 0x5f, 0xa9, 0xb7, 0xca,
-	0x9d, 0x3e, 0xbd, 0x34, 0xf7, 0x62, 0xa6, 0xdf, 0x21, 0xf4, 0xa2, 0x67, 0x43, 0xee, 0x97, 0x4d,
-	0x6e, 0xbc, 0x05, 0xb2, 0xaf, 0x51, 0x57, 0x7c, 0x5b, 0x33, 0x7c, 0x0e, 0xda, 0x9a, 0xa7, 0x71,
-	0x91, 0xf5, 0x65, 0x9e, 0xac, 0x79, 0x3a, 0xcf, 0x8c, 0x37, 0x70, 0xe1, 0xb0, 0x61, 0x33, 0x47,
-	0xb8, 0xdf, 0x0a, 0xe8, 0x3b, 0xb0, 0x57, 0xfc, 0x08, 0x5a, 0x2d, 0x13, 0xd9, 0xd4, 0x2d, 0xa9,
-	0x4f, 0x5f, 0x0f, 0x3b, 0xfb, 0x97, 0x35, 0x83, 0x16, 0xa4, 0x7d, 0x02, 0xde, 0xc0, 0xb8, 0xd8,
-	0x24, 0x39, 0x8b, 0x1b, 0x51, 0xb6, 0x73, 0x8d, 0xe9, 0xa8, 0x0d, 0x44, 0xa2, 0x34, 0xee, 0x41,
-	0xeb, 0x70, 0x44, 0xd0, 0x23, 0x77, 0xe1, 0x7a, 0xdf, 0xdd, 0x38, 0x08, 0x1f, 0xc2, 0x28, 0x20,
-	0x27, 0xf8, 0x14, 0xce, 0x7d, 0xdb, 0xb5, 0xe6, 0xae, 0x43, 0x14, 0x1c, 0xc1, 0x99, 0xe5, 0xb9,
-	0x36, 0x51, 0x11, 0x40, 0xfb, 0xf2, 0x30, 0xff, 0x66, 0x5b, 0xe4, 0xf4, 0xdd, 0x67, 0x38, 0xef,
-	0x8d, 0xc1, 0x4b, 0x98, 0xec, 0x14, 0x7c, 0xea, 0x59, 0xd1, 0x2c, 0x24, 0x27, 0x7f, 0xb3, 0x1c,
-	0xea, 0xcf, 0x88, 0x82, 0x3a, 0xc0, 0x22, 0x7a, 0xb4, 0xa9, 0x6b, 0x87, 0x76, 0x40, 0x54, 0xd4,
-	0x40, 0x75, 0x3c, 0x72, 0x3a, 0xfd, 0xa5, 0x00, 0x38, 0xc5, 0x6a, 0xd6, 0x6d, 0x11, 0x6d, 0x18,
-	0xed, 0xfc, 0xc4, 0x9b, 0xe1, 0x94, 0xff, 0x6d, 0xf2, 0xfa, 0xd5, 0xe1, 0xcf, 0xde, 0xb0, 0x7b,
-	0xd0, 0x3a, 0x5b, 0xf0, 0xe5, 0x21, 0xab, 0x3a, 0x89, 0xeb, 0xe3, 0x2e, 0x3e, 0x3e, 0xfb, 0x31,
-	0xb8, 0xa4, 0x54, 0x6b, 0x8f, 0xeb, 0xc3, 0x9f, 0x00, 0x00, 0x00, 0xff, 0xff, 0x04, 0x9d, 0x88,
-	0xb1, 0x76, 0x02, 0x00, 0x00,
+	// 1271 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xa4, 0x56, 0xcd, 0x6e, 0xdb, 0x46,
+	0x10, 0x36, 0x29, 0x91, 0x92, 0x46, 0xb6, 0x44, 0xaf, 0xd3, 0x44, 0x71, 0xe2, 0xd6, 0x11, 0xd0,
+	0x40, 0x31, 0x10, 0x37, 0x70, 0x4e, 0x6d, 0x0f, 0x29, 0x23, 0xd1, 0x32, 0x13, 0x99, 0x62, 0x96,
+	0x14, 0x82, 0xf6, 0x42, 0xd0, 0xe2, 0x5a, 0x65, 0x2a, 0x91, 0x0c, 0x49, 0x35, 0x4d, 0xde, 0xa1,
+	0x0f, 0xd4, 0x07, 0xe8, 0xad, 0x97, 0x3e, 0x43, 0x9f, 0xa2, 0x40, 0x0f, 0xc5, 0xfe, 0xd0, 0x12,
+	0x5d, 0x3b, 0x2e, 0x90, 0x1b, 0xf7, 0x9b, 0x6f, 0x87, 0x33, 0xdf, 0xce, 0xcc, 0x2e, 0xdc, 0x4e,
+	0xd2, 0x38, 0x8f, 0xbf, 0x9a, 0x85, 0xe7, 0xd3, 0x94, 0xf8, 0x79, 0x9c, 0x1e, 0x32, 0x00, 0x41,
+	0x4a, 0xa2, 0x80, 0xa4, 0x01, 0x59, 0xc4, 0xdd, 0x3f, 0x65, 0x68, 0x3b, 0xb9, 0x9f, 0xe6, 0x2f,
+	0xe2, 0x33, 0x4c, 0xde, 0x2e, 0x49, 0x96, 0x23, 0x04, 0xd5, 0xc8, 0x5f, 0x90, 0x8e, 0xb4, 0x2f,
+	0xf5, 0x1a, 0x98, 0x7d, 0xa3, 0x6f, 0xa1, 0x9d, 0xa4, 0x71, 0xb0, 0x9c, 0xe6, 0x5e, 0x1e, 0x7b,
+	0xc9, 0x7c, 0x39, 0xeb, 0xc8, 0xfb, 0x52, 0xaf, 0x75, 0xb4, 0x73, 0xb8, 0xf2, 0x76, 0x68, 0x73,
+	0x0a, 0xde, 0x12, 0x5c, 0x37, 0xb6, 0xe7, 0xcb, 0x19, 0xfa, 0x02, 0x9a, 0xe7, 0xa9, 0xbf, 0x20,
+	0xde, 0x34, 0x5e, 0x46, 0x79, 0xa7, 0xb2, 0x2f, 0xf5, 0x14, 0x0c, 0x0c, 0xea, 0x53, 0x84, 0x12,
+	0x32, 0x1a, 0x84, 0xe7, 0x47, 0xb3, 0x39, 0xe9, 0x54, 0xf7, 0xa5, 0x9e, 0x8c, 0x81, 0x41, 0x3a,
+	0x45, 0xd0, 0x3d, 0x68, 0x90, 0x28, 0x10, 0x66, 0x85, 0x99, 0xeb, 0x24, 0x0a, 0xb8, 0xf1, 0x00,
+	0xb6, 0x99, 0xaf, 0xcc, 0x4b, 0x48, 0xea, 0x65, 0x64, 0x1a, 0x47, 0x41, 0x47, 0x65, 0x3f, 0x69,
+	0x73, 0x83, 0x4d, 0x52, 0x87, 0xc1, 0xe8, 0x31, 0xd4, 0xde, 0x2e, 0xfd, 0x79, 0x98, 0xbf, 0xef,
+	0xd4, 0xfe, 0x1b, 0xff, 0x2b, 0x6e, 0xc2, 0x05, 0x07, 0xdd, 0x85, 0x7a, 0x36, 0x25, 0x11, 0xf1,
+	0xc2, 0xa0, 0x53, 0x67, 0x72, 0xd4, 0xd8, 0xda, 0x0c, 0x50, 0x07, 0x6a, 0x49, 0x4a, 0x7e, 0x0e,
+	0xc9, 0xbb, 0x4e, 0x63, 0x5f, 0xea, 0xd5, 0x71, 0xb1, 0xec, 0xbe, 0x00, 0x6d, 0x25, 0x69, 0x96,
+	0xc4, 0x51, 0x46, 0xd0, 0x67, 0xa0, 0xbe, 0x89, 0xcf, 0xa8, 0x1b, 0xae, 0xaa, 0xf2, 0x26, 0x3e,
+	0x33, 0x03, 0x9a, 0x78, 0xfc, 0x2e, 0x22, 0xa9, 0x97, 0xc7, 0x3f, 0x91, 0x88, 0x49, 0xda, 0xc0,
+	0xc0, 0x20, 0x97, 0x22, 0xdd, 0x87, 0xb0, 0x35, 0x24, 0xeb, 0x87, 0x73, 0xb5, 0xa3, 0x6e, 0x0f,
+	0xda, 0xaf, 0xfd, 0x7c, 0xfa, 0xe3, 0xcd, 0xcc, 0xdf, 0x65, 0x68, 0x0d, 0x49, 0x29, 0xb8, 0xaf,
+	0x41, 0xcd, 0x72, 0x3f, 0x5f, 0x66, 0x8c, 0xd9, 0x3a, 0x7a, 0xb0, 0xae, 0x49, 0x99, 0x7b, 0xe8,
+	0x30, 0x22, 0x16, 0x1b, 0xe8, 0xc1, 0x84, 0x0b, 0x7f, 0x46, 0xbc, 0x65, 0x3a, 0x17, 0xe1, 0xd7,
+	0x19, 0x30, 0x49, 0xe7, 0xe8, 0x00, 0x14, 0x92, 0xa6, 0x71, 0xca, 0x4e, 0xbc, 0x79, 0x74, 0x6b,
+	0xdd, 0xed, 0x8b, 0xf8, 0xcc, 0xa0, 0x36, 0xcc, 0x29, 0xe8, 0x29, 0xd4, 0x93, 0x34, 0x9e, 0xa5,
+	0x24, 0xcb, 0xd8, 0xf9, 0x37, 0x8f, 0xee, 0x5c, 0xa2, 0xdb, 0xc2, 0x8c, 0x2f, 0x88, 0xf4, 0xe4,
+	0x85, 0xe8, 0xde, 0x2a, 0x0a, 0x85, 0x45, 0xd1, 0x16, 0x06, 0x53, 0x04, 0xd3, 0xb5, 0x40, 0xe5,
+	0xb1, 0x23, 0x04, 0xad, 0x89, 0xf5, 0xd2, 0x1a, 0xbf, 0xb6, 0x3c, 0xc7, 0xd5, 0xdd, 0x89, 0xa3,
+	0x6d, 0xa0, 0x26, 0xd4, 0x6c, 0xc3, 0x1a, 0x98, 0xd6, 0x50, 0x93, 0x50, 0x1d, 0xaa, 0x83, 0xb1,
+	0x65, 0x68, 0x32, 0x02, 0x50, 0x8f, 0x75, 0x73, 0x64, 0x0c, 0xb4, 0x0a, 0xda, 0x82, 0x46, 0x5f,
+	0xb7, 0xfa, 0xc6, 0x88, 0x2e, 0xab, 0xdd, 0x3f, 0x64, 0x68, 0xae, 0x45, 0x85, 0x9e, 0x82, 0x92,
+	0xe5, 0xfe, 0x8c, 0x08, 0x0d, 0xf7, 0xae, 0x89, 0x9e, 0x0a, 0x38, 0x23, 0x98, 0x73, 0xd1, 0x23,
+	0xd0, 0x44, 0xe9, 0x4e, 0xe3, 0x45, 0x32, 0x27, 0x39, 0x09, 0x98, 0x8a, 0x95, 0xa2, 0x72, 0xfb,
+	0x05, 0x8c, 0x1e, 0xc0, 0xa6, 0xa0, 0xe6, 0x71, 0xee, 0xcf, 0x99, 0xa6, 0x15, 0xcc, 0x1b, 0x2b,
+	0x73, 0x29, 0x84, 0xf6, 0x80, 0xf7, 0x8c, 0x97, 0x87, 0x0b, 0xde, 0x45, 0x15, 0xdc, 0x60, 0x88,
+	0x1b, 0x2e, 0x08, 0x2d, 0xb6, 0x65, 0x12, 0xf8, 0x39, 0xe1, 0x76, 0x85, 0xd9, 0x81, 0x43, 0x8c,
+	0x70, 0x17, 0x68, 0x53, 0x71, 0xab, 0xca, 0xac, 0x35, 0x12, 0x05, 0xd4, 0xd4, 0xf5, 0x40, 0x61,
+	0x81, 0xa3, 0x6d, 0xd8, 0x5a, 0x13, 0x6f, 0x68, 0x68, 0x1b, 0xe8, 0x16, 0x68, 0x36, 0x36, 0x6c,
+	0x1d, 0x9b, 0xd6, 0xd0, 0xd3, 0x1d, 0xc7, 0x70, 0x1d, 0x4d, 0xa2, 0x72, 0x61, 0xc3, 0x1a, 0x18,
+	0x14, 0xd5, 0x64, 0xd4, 0x86, 0x66, 0x7f, 0x7c, 0x6a, 0x8f, 0x1d, 0xd3, 0xa5, 0x00, 0x93, 0x73,
+	0x62, 0x8f, 0xc6, 0x3a, 0xd3, 0xbc, 0xda, 0xfd, 0x47, 0x82, 0x7a, 0x51, 0x13, 0xe8, 0x49, 0x59,
+	0xcb, 0xdd, 0xab, 0x0a, 0xa7, 0x2c, 0xe4, 0x2d, 0x50, 0x98, 0x12, 0x42, 0x3d, 0xbe, 0xa0, 0x3d,
+	0xba, 0x20, 0x59, 0x46, 0x3d, 0x55, 0x78, 0xf7, 0x8a, 0x65, 0xf7, 0x57, 0xe9, 0x23, 0x09, 0xdd,
+	0x81, 0x1d, 0x17, 0xeb, 0x96, 0x73, 0x3c, 0xc6, 0xa7, 0x9e, 0x6b, 0x9c, 0xda, 0x23, 0xdd, 0x35,
+	0x68, 0x4e, 0x94, 0xcb, 0x62, 0x2e, 0xd2, 0x94, 0x91, 0x06, 0x9b, 0x02, 0x7a, 0xae, 0x0f, 0x86,
+	0x86, 0x56, 0xa1, 0x08, 0x4f, 0xdc, 0x3b, 0xc6, 0xfa, 0xa9, 0xa1, 0x55, 0x8b, 0xdc, 0xcd, 0x91,
+	0xe1, 0x0d, 0xcd, 0x63, 0x4d, 0xa1, 0x14, 0xc7, 0x35, 0xdd, 0xfe, 0x89, 0xe7, 0x9a, 0x23, 0xc3,
+	0xd1, 0x54, 0x3a, 0x33, 0xfa, 0x7e, 0x34, 0x25, 0xf3, 0x1b, 0x1b, 0xf8, 0xe6, 0x99, 0xb1, 0x03,
+	0xdb, 0x6b, 0xbe, 0x78, 0xdf, 0x76, 0xff, 0x96, 0xa0, 0x3d, 0x0a, 0x33, 0xda, 0xcb, 0x59, 0xf1,
+	0x83, 0x2f, 0xa1, 0x15, 0x27, 0x24, 0xa5, 0x57, 0x83, 0x70, 0xa6, 0x32, 0x67, 0x5b, 0x05, 0xca,
+	0xfc, 0x7d, 0xca, 0x78, 0x78, 0x0c, 0x35, 0x71, 0x15, 0x7c, 0xec, 0xba, 0x28, 0x38, 0xa5, 0x71,
+	0xab, 0x94, 0xc7, 0xed, 0x3d, 0x68, 0x24, 0xb4, 0xc3, 0xb3, 0xf0, 0x03, 0x11, 0x37, 0x48, 0x9d,
+	0x02, 0x4e, 0xf8, 0x81, 0xd0, 0xc2, 0x67, 0x46, 0x9e, 0x44, 0x95, 0xed, 0x64, 0x74, 0x2e, 0xc8,
+	0x39, 0x68, 0xab, 0xd4, 0xc5, 0xcc, 0x3b, 0x80, 0xea, 0x9b, 0xf8, 0x8c, 0xa6, 0x54, 0xe9, 0x35,
+	0x8f, 0x6e, 0x5f, 0xaa, 0x30, 0x67, 0xb9, 0x58, 0xf8, 0xe9, 0x7b, 0xcc, 0x38, 0xe8, 0x21, 0xb4,
+	0x23, 0xf2, 0x4b, 0xee, 0xad, 0xfd, 0x83, 0xab, 0xbe, 0x45, 0x61, 0xfb, 0xe2, 0x3f, 0x7f, 0x49,
+	0x00, 0xab, 0xcd, 0xd7, 0x9d, 0xdf, 0x4a, 0x4e, 0xf9, 0x13, 0xe4, 0xac, 0xfc, 0x0f, 0x39, 0x3b,
+	0x50, 0x9b, 0xfa, 0x49, 0x1e, 0xc6, 0x85, 0x26, 0xc5, 0x92, 0xd6, 0x10, 0x7b, 0x13, 0x94, 0x47,
+	0x01, 0x87, 0x8a, 0x51, 0x70, 0x71, 0x12, 0x6a, 0xe9, 0x24, 0x68, 0x79, 0x51, 0x35, 0x1d, 0xba,
+	0x2c, 0x4a, 0xa9, 0xfb, 0x0c, 0xd0, 0x3a, 0x28, 0x44, 0x7e, 0x04, 0x2a, 0xdb, 0x55, 0xc8, 0xbc,
+	0xbd, 0x1e, 0x2e, 0xe3, 0x62, 0x41, 0xe8, 0x7e, 0x03, 0x0a, 0x03, 0x50, 0x0b, 0xe4, 0x0b, 0xc5,
+	0xe4, 0x90, 0xcd, 0xbd, 0x20, 0xcc, 0x92, 0xb9, 0xff, 0xde, 0x8b, 0x8a, 0x06, 0x6f, 0xe0, 0xa6,
+	0xc0, 0x2c, 0x7f, 0x41, 0x0e, 0x4e, 0xa1, 0x26, 0x6e, 0x6e, 0xb4, 0x03, 0xed, 0xa2, 0x9b, 0x5f,
+	0x4d, 0xf4, 0x91, 0xe9, 0x7e, 0xaf, 0x6d, 0xa0, 0x06, 0x28, 0x03, 0xac, 0x1f, 0xbb, 0x9a, 0x84,
+	0x36, 0xa1, 0xee, 0xb8, 0xba, 0x35, 0xd0, 0xf1, 0x40, 0x93, 0xe9, 0xa0, 0x3f, 0x31, 0x87, 0x27,
+	0x5a, 0x85, 0x0e, 0x7a, 0x7b, 0xec, 0xb8, 0x06, 0xd6, 0xaa, 0x07, 0xdf, 0x41, 0x4d, 0x48, 0xb9,
+	0xee, 0xce, 0xc6, 0xe3, 0xc1, 0xa4, 0xef, 0x6a, 0x1b, 0x74, 0xd7, 0x10, 0xdb, 0x7d, 0x4d, 0x42,
+	0x2d, 0x80, 0x97, 0x93, 0xe7, 0x06, 0xb6, 0x0c, 0x3a, 0x1f, 0x64, 0xa4, 0x82, 0x3c, 0x1c, 0x6b,
+	0x95, 0xa3, 0xdf, 0x2a, 0x00, 0xc3, 0xf0, 0xbc, 0xcf, 0x9f, 0x5d, 0xc8, 0x80, 0x7a, 0xf1, 0x20,
+	0x40, 0xf7, 0x4a, 0x12, 0x94, 0x5f, 0x5e, 0xbb, 0xf7, 0xaf, 0x36, 0x0a, 0x35, 0x9f, 0x81, 0xca,
+	0xcb, 0x03, 0xdd, 0xbd, 0xaa, 0x64, 0xb8, 0x8b, 0xdd, 0xeb, 0xab, 0x89, 0xc6, 0x51, 0x3c, 0x12,
+	0xca, 0x71, 0x5c, 0x7a, 0x3a, 0x7c, 0xcc, 0xc9, 0x13, 0x09, 0x9d, 0x40, 0xe3, 0x62, 0xbe, 0xa0,
+	0x52, 0xc8, 0x97, 0x47, 0xd8, 0xee, 0xde, 0x35, 0xd6, 0x55, 0x40, 0x45, 0x63, 0x96, 0x03, 0xba,
+	0x34, 0xa9, 0x76, 0xef, 0x5f, 0x6d, 0x14, 0x6e, 0x5e, 0x02, 0xac, 0x8a, 0x0f, 0xed, 0x5d, 0xe6,
+	0x96, 0x2a, 0x75, 0xf7, 0xf3, 0xeb, 0xcc, 0xdc, 0xd9, 0xf3, 0xcd, 0x1f, 0xd6, 0xde, 0xc7, 0x67,
+	0x2a, 0x7b, 0x32, 0x3f, 0xfd, 0x17, 0x00, 0x00, 0xff, 0xff, 0x01, 0x00, 0x00, 0xff, 0xff, 0x7b,
+	0x54, 0x17, 0x56, 0x4c, 0x0b, 0x00, 0x00,
 }
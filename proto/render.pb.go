@@ -25,10 +25,24 @@ type RenderRequest struct {
 	ObjPath string `protobuf:"bytes,2,opt,name=obj_path,json=objPath" json:"obj_path,omitempty"`
 	// assets (like material files and images) to be associated with the object
 	Assets []string `protobuf:"bytes,3,rep,name=assets" json:"assets,omitempty"`
-	// scene rotation (in radians)
+	// scene rotation (in degrees)
 	Rotation float32 `protobuf:"fixed32,4,opt,name=rotation" json:"rotation,omitempty"`
 	// num iterations
 	Iterations int32 `protobuf:"varint,5,opt,name=iterations" json:"iterations,omitempty"`
+	// Size of the output image, in pixels. Defaults to 300x300.
+	Width  int32 `protobuf:"varint,6,opt,name=width" json:"width,omitempty"`
+	Height int32 `protobuf:"varint,7,opt,name=height" json:"height,omitempty"`
+	// Number of paths traced through each pixel per iteration. Defaults to 16.
+	SamplesPerPixel int32 `protobuf:"varint,8,opt,name=samples_per_pixel,json=samplesPerPixel" json:"samples_per_pixel,omitempty"`
+	// Path of a JSON scene description to render the object in. The object is
+	// rendered in the default scene if this is empty.
+	ScenePath string `protobuf:"bytes,9,opt,name=scene_path,json=scenePath" json:"scene_path,omitempty"`
+	// Only render this part of the image, for splitting large images between
+	// several render nodes. The output image is the size of the region. The
+	// whole image is rendered if this is unset.
+	Region *Region `protobuf:"bytes,10,opt,name=region" json:"region,omitempty"`
+	// GCS path to write previews into, for RenderFrameProgressive.
+	PreviewOutputBase string `protobuf:"bytes,11,opt,name=preview_output_base,json=previewOutputBase" json:"preview_output_base,omitempty"`
 }
 
 func (m *RenderRequest) Reset()                    { *m = RenderRequest{} }
@@ -71,6 +85,89 @@ func (m *RenderRequest) GetIterations() int32 {
 	return 0
 }
 
+func (m *RenderRequest) GetWidth() int32 {
+	if m != nil {
+		return m.Width
+	}
+	return 0
+}
+
+func (m *RenderRequest) GetHeight() int32 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
+func (m *RenderRequest) GetSamplesPerPixel() int32 {
+	if m != nil {
+		return m.SamplesPerPixel
+	}
+	return 0
+}
+
+func (m *RenderRequest) GetScenePath() string {
+	if m != nil {
+		return m.ScenePath
+	}
+	return ""
+}
+
+func (m *RenderRequest) GetRegion() *Region {
+	if m != nil {
+		return m.Region
+	}
+	return nil
+}
+
+func (m *RenderRequest) GetPreviewOutputBase() string {
+	if m != nil {
+		return m.PreviewOutputBase
+	}
+	return ""
+}
+
+// A rectangle of an image, in pixels from its top left corner.
+type Region struct {
+	X      int32 `protobuf:"varint,1,opt,name=x" json:"x,omitempty"`
+	Y      int32 `protobuf:"varint,2,opt,name=y" json:"y,omitempty"`
+	Width  int32 `protobuf:"varint,3,opt,name=width" json:"width,omitempty"`
+	Height int32 `protobuf:"varint,4,opt,name=height" json:"height,omitempty"`
+}
+
+func (m *Region) Reset()                    { *m = Region{} }
+func (m *Region) String() string            { return proto.CompactTextString(m) }
+func (*Region) ProtoMessage()               {}
+func (*Region) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{1} }
+
+func (m *Region) GetX() int32 {
+	if m != nil {
+		return m.X
+	}
+	return 0
+}
+
+func (m *Region) GetY() int32 {
+	if m != nil {
+		return m.Y
+	}
+	return 0
+}
+
+func (m *Region) GetWidth() int32 {
+	if m != nil {
+		return m.Width
+	}
+	return 0
+}
+
+func (m *Region) GetHeight() int32 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
 type RenderResponse struct {
 	// GCS path image was written to.
 	GcsOutput string `protobuf:"bytes,1,opt,name=gcs_output,json=gcsOutput" json:"gcs_output,omitempty"`
@@ -79,7 +176,7 @@ type RenderResponse struct {
 func (m *RenderResponse) Reset()                    { *m = RenderResponse{} }
 func (m *RenderResponse) String() string            { return proto.CompactTextString(m) }
 func (*RenderResponse) ProtoMessage()               {}
-func (*RenderResponse) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{1} }
+func (*RenderResponse) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{2} }
 
 func (m *RenderResponse) GetGcsOutput() string {
 	if m != nil {
@@ -88,9 +185,201 @@ func (m *RenderResponse) GetGcsOutput() string {
 	return ""
 }
 
+type RenderProgress struct {
+	// The iteration the image is from, counting from 1. The rough preview of a
+	// single-iteration frame counts as iteration 1.
+	Iteration int32 `protobuf:"varint,1,opt,name=iteration" json:"iteration,omitempty"`
+	// GCS path image was written to.
+	GcsOutput string `protobuf:"bytes,2,opt,name=gcs_output,json=gcsOutput" json:"gcs_output,omitempty"`
+	// Whether this is the final image, rather than a preview.
+	Final bool `protobuf:"varint,3,opt,name=final" json:"final,omitempty"`
+}
+
+func (m *RenderProgress) Reset()                    { *m = RenderProgress{} }
+func (m *RenderProgress) String() string            { return proto.CompactTextString(m) }
+func (*RenderProgress) ProtoMessage()               {}
+func (*RenderProgress) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{3} }
+
+func (m *RenderProgress) GetIteration() int32 {
+	if m != nil {
+		return m.Iteration
+	}
+	return 0
+}
+
+func (m *RenderProgress) GetGcsOutput() string {
+	if m != nil {
+		return m.GcsOutput
+	}
+	return ""
+}
+
+func (m *RenderProgress) GetFinal() bool {
+	if m != nil {
+		return m.Final
+	}
+	return false
+}
+
+type RenderFramesRequest struct {
+	// The scene to render. Its gcs_output_base, rotation and region are
+	// ignored in favour of those of each frame.
+	Scene  *RenderRequest               `protobuf:"bytes,1,opt,name=scene" json:"scene,omitempty"`
+	Frames []*RenderFramesRequest_Frame `protobuf:"bytes,2,rep,name=frames" json:"frames,omitempty"`
+}
+
+func (m *RenderFramesRequest) Reset()                    { *m = RenderFramesRequest{} }
+func (m *RenderFramesRequest) String() string            { return proto.CompactTextString(m) }
+func (*RenderFramesRequest) ProtoMessage()               {}
+func (*RenderFramesRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{4} }
+
+func (m *RenderFramesRequest) GetScene() *RenderRequest {
+	if m != nil {
+		return m.Scene
+	}
+	return nil
+}
+
+func (m *RenderFramesRequest) GetFrames() []*RenderFramesRequest_Frame {
+	if m != nil {
+		return m.Frames
+	}
+	return nil
+}
+
+type RenderFramesRequest_Frame struct {
+	// GCS path to write output image into.
+	GcsOutputBase string `protobuf:"bytes,1,opt,name=gcs_output_base,json=gcsOutputBase" json:"gcs_output_base,omitempty"`
+	// scene rotation (in degrees)
+	Rotation float32 `protobuf:"fixed32,2,opt,name=rotation" json:"rotation,omitempty"`
+	// Part of the image to render, as in RenderRequest.
+	Region *Region `protobuf:"bytes,3,opt,name=region" json:"region,omitempty"`
+}
+
+func (m *RenderFramesRequest_Frame) Reset()                    { *m = RenderFramesRequest_Frame{} }
+func (m *RenderFramesRequest_Frame) String() string            { return proto.CompactTextString(m) }
+func (*RenderFramesRequest_Frame) ProtoMessage()               {}
+func (*RenderFramesRequest_Frame) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{4, 0} }
+
+func (m *RenderFramesRequest_Frame) GetGcsOutputBase() string {
+	if m != nil {
+		return m.GcsOutputBase
+	}
+	return ""
+}
+
+func (m *RenderFramesRequest_Frame) GetRotation() float32 {
+	if m != nil {
+		return m.Rotation
+	}
+	return 0
+}
+
+func (m *RenderFramesRequest_Frame) GetRegion() *Region {
+	if m != nil {
+		return m.Region
+	}
+	return nil
+}
+
+type RenderFramesResponse struct {
+	// Index into the request's frames of the frame that was rendered.
+	Frame int32 `protobuf:"varint,1,opt,name=frame" json:"frame,omitempty"`
+	// GCS path image was written to.
+	GcsOutput string `protobuf:"bytes,2,opt,name=gcs_output,json=gcsOutput" json:"gcs_output,omitempty"`
+}
+
+func (m *RenderFramesResponse) Reset()                    { *m = RenderFramesResponse{} }
+func (m *RenderFramesResponse) String() string            { return proto.CompactTextString(m) }
+func (*RenderFramesResponse) ProtoMessage()               {}
+func (*RenderFramesResponse) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{5} }
+
+func (m *RenderFramesResponse) GetFrame() int32 {
+	if m != nil {
+		return m.Frame
+	}
+	return 0
+}
+
+func (m *RenderFramesResponse) GetGcsOutput() string {
+	if m != nil {
+		return m.GcsOutput
+	}
+	return ""
+}
+
+type GetLoadRequest struct {
+}
+
+func (m *GetLoadRequest) Reset()                    { *m = GetLoadRequest{} }
+func (m *GetLoadRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetLoadRequest) ProtoMessage()               {}
+func (*GetLoadRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{6} }
+
+type RenderLoad struct {
+	// How many renders are running, and the most that may run at once.
+	Active    int32 `protobuf:"varint,1,opt,name=active" json:"active,omitempty"`
+	MaxActive int32 `protobuf:"varint,2,opt,name=max_active,json=maxActive" json:"max_active,omitempty"`
+	// How many renders are waiting to start, and the most that may wait.
+	// Renders beyond that are refused with RESOURCE_EXHAUSTED.
+	Queued    int32 `protobuf:"varint,3,opt,name=queued" json:"queued,omitempty"`
+	MaxQueued int32 `protobuf:"varint,4,opt,name=max_queued,json=maxQueued" json:"max_queued,omitempty"`
+	// How long to wait before sending another render, in milliseconds, or 0 if
+	// one would be accepted now. Refused renders carry the same hint in their
+	// retry-after-ms trailer.
+	RetryAfterMs int64 `protobuf:"varint,5,opt,name=retry_after_ms,json=retryAfterMs" json:"retry_after_ms,omitempty"`
+}
+
+func (m *RenderLoad) Reset()                    { *m = RenderLoad{} }
+func (m *RenderLoad) String() string            { return proto.CompactTextString(m) }
+func (*RenderLoad) ProtoMessage()               {}
+func (*RenderLoad) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{7} }
+
+func (m *RenderLoad) GetActive() int32 {
+	if m != nil {
+		return m.Active
+	}
+	return 0
+}
+
+func (m *RenderLoad) GetMaxActive() int32 {
+	if m != nil {
+		return m.MaxActive
+	}
+	return 0
+}
+
+func (m *RenderLoad) GetQueued() int32 {
+	if m != nil {
+		return m.Queued
+	}
+	return 0
+}
+
+func (m *RenderLoad) GetMaxQueued() int32 {
+	if m != nil {
+		return m.MaxQueued
+	}
+	return 0
+}
+
+func (m *RenderLoad) GetRetryAfterMs() int64 {
+	if m != nil {
+		return m.RetryAfterMs
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*RenderRequest)(nil), "renderdemo.RenderRequest")
+	proto.RegisterType((*Region)(nil), "renderdemo.Region")
 	proto.RegisterType((*RenderResponse)(nil), "renderdemo.RenderResponse")
+	proto.RegisterType((*RenderProgress)(nil), "renderdemo.RenderProgress")
+	proto.RegisterType((*RenderFramesRequest)(nil), "renderdemo.RenderFramesRequest")
+	proto.RegisterType((*RenderFramesRequest_Frame)(nil), "renderdemo.RenderFramesRequest.Frame")
+	proto.RegisterType((*RenderFramesResponse)(nil), "renderdemo.RenderFramesResponse")
+	proto.RegisterType((*GetLoadRequest)(nil), "renderdemo.GetLoadRequest")
+	proto.RegisterType((*RenderLoad)(nil), "renderdemo.RenderLoad")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -105,6 +394,9 @@ const _ = grpc.SupportPackageIsVersion4
 
 type RenderClient interface {
 	RenderFrame(ctx context.Context, in *RenderRequest, opts ...grpc.CallOption) (*RenderResponse, error)
+	RenderFrames(ctx context.Context, in *RenderFramesRequest, opts ...grpc.CallOption) (Render_RenderFramesClient, error)
+	RenderFrameProgressive(ctx context.Context, in *RenderRequest, opts ...grpc.CallOption) (Render_RenderFrameProgressiveClient, error)
+	GetLoad(ctx context.Context, in *GetLoadRequest, opts ...grpc.CallOption) (*RenderLoad, error)
 }
 
 type renderClient struct {
@@ -124,10 +416,86 @@ func (c *renderClient) RenderFrame(ctx context.Context, in *RenderRequest, opts
 	return out, nil
 }
 
+func (c *renderClient) RenderFrames(ctx context.Context, in *RenderFramesRequest, opts ...grpc.CallOption) (Render_RenderFramesClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Render_serviceDesc.Streams[0], c.cc, "/renderdemo.Render/RenderFrames", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &renderRenderFramesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Render_RenderFramesClient interface {
+	Recv() (*RenderFramesResponse, error)
+	grpc.ClientStream
+}
+
+type renderRenderFramesClient struct {
+	grpc.ClientStream
+}
+
+func (x *renderRenderFramesClient) Recv() (*RenderFramesResponse, error) {
+	m := new(RenderFramesResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *renderClient) RenderFrameProgressive(ctx context.Context, in *RenderRequest, opts ...grpc.CallOption) (Render_RenderFrameProgressiveClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Render_serviceDesc.Streams[1], c.cc, "/renderdemo.Render/RenderFrameProgressive", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &renderRenderFrameProgressiveClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Render_RenderFrameProgressiveClient interface {
+	Recv() (*RenderProgress, error)
+	grpc.ClientStream
+}
+
+type renderRenderFrameProgressiveClient struct {
+	grpc.ClientStream
+}
+
+func (x *renderRenderFrameProgressiveClient) Recv() (*RenderProgress, error) {
+	m := new(RenderProgress)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *renderClient) GetLoad(ctx context.Context, in *GetLoadRequest, opts ...grpc.CallOption) (*RenderLoad, error) {
+	out := new(RenderLoad)
+	err := grpc.Invoke(ctx, "/renderdemo.Render/GetLoad", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for Render service
 
 type RenderServer interface {
 	RenderFrame(context.Context, *RenderRequest) (*RenderResponse, error)
+	RenderFrames(*RenderFramesRequest, Render_RenderFramesServer) error
+	RenderFrameProgressive(*RenderRequest, Render_RenderFrameProgressiveServer) error
+	GetLoad(context.Context, *GetLoadRequest) (*RenderLoad, error)
 }
 
 func RegisterRenderServer(s *grpc.Server, srv RenderServer) {
@@ -152,6 +520,66 @@ func _Render_RenderFrame_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Render_RenderFrames_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(RenderFramesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RenderServer).RenderFrames(m, &renderRenderFramesServer{stream})
+}
+
+type Render_RenderFramesServer interface {
+	Send(*RenderFramesResponse) error
+	grpc.ServerStream
+}
+
+type renderRenderFramesServer struct {
+	grpc.ServerStream
+}
+
+func (x *renderRenderFramesServer) Send(m *RenderFramesResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Render_RenderFrameProgressive_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(RenderRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RenderServer).RenderFrameProgressive(m, &renderRenderFrameProgressiveServer{stream})
+}
+
+type Render_RenderFrameProgressiveServer interface {
+	Send(*RenderProgress) error
+	grpc.ServerStream
+}
+
+type renderRenderFrameProgressiveServer struct {
+	grpc.ServerStream
+}
+
+func (x *renderRenderFrameProgressiveServer) Send(m *RenderProgress) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Render_GetLoad_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLoadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RenderServer).GetLoad(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/renderdemo.Render/GetLoad",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RenderServer).GetLoad(ctx, req.(*GetLoadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Render_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "renderdemo.Render",
 	HandlerType: (*RenderServer)(nil),
@@ -160,29 +588,68 @@ var _Render_serviceDesc = grpc.ServiceDesc{
 			MethodName: "RenderFrame",
 			Handler:    _Render_RenderFrame_Handler,
 		},
+		{
+			MethodName: "GetLoad",
+			Handler:    _Render_GetLoad_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "RenderFrames",
+			Handler:       _Render_RenderFrames_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "RenderFrameProgressive",
+			Handler:       _Render_RenderFrameProgressive_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/render.proto",
 }
 
 func init() { proto.RegisterFile("proto/render.proto", fileDescriptor1) }
 
 var fileDescriptor1 = []byte{
-	// 241 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x6c, 0x90, 0xcf, 0x4a, 0xc3, 0x40,
-	0x10, 0xc6, 0xd9, 0xd6, 0xc6, 0x66, 0xb4, 0x0a, 0x73, 0x90, 0x6d, 0x40, 0x09, 0x3d, 0x48, 0x4e,
-	0x29, 0xe8, 0x1b, 0x14, 0xf1, 0xa8, 0xb2, 0x47, 0x2f, 0x61, 0xd3, 0x0e, 0xfd, 0x03, 0xcd, 0xc4,
-	0x9d, 0xc9, 0x2b, 0xf9, 0x9c, 0xc2, 0xa6, 0xb6, 0x15, 0xbc, 0xed, 0x6f, 0x76, 0x97, 0xf9, 0x7d,
-	0x1f, 0x60, 0x1b, 0x58, 0x79, 0x1e, 0xa8, 0x59, 0x51, 0x28, 0x23, 0x20, 0xf4, 0xb4, 0xa2, 0x3d,
-	0xcf, 0xbe, 0x0d, 0x4c, 0x5c, 0x44, 0x47, 0x5f, 0x1d, 0x89, 0xe2, 0x23, 0xdc, 0xae, 0x97, 0x52,
-	0x71, 0xa7, 0x6d, 0xa7, 0x55, 0xed, 0x85, 0xac, 0xc9, 0x4d, 0x91, 0xba, 0xc9, 0x7a, 0x29, 0xef,
-	0x71, 0xba, 0xf0, 0x42, 0x38, 0x85, 0x31, 0xd7, 0xbb, 0xaa, 0xf5, 0xba, 0xb1, 0x83, 0xf8, 0xe0,
-	0x92, 0xeb, 0xdd, 0x87, 0xd7, 0x0d, 0xde, 0x41, 0xe2, 0x45, 0x48, 0xc5, 0x0e, 0xf3, 0x61, 0x91,
-	0xba, 0x03, 0x61, 0x06, 0xe3, 0xc0, 0xea, 0x75, 0xcb, 0x8d, 0xbd, 0xc8, 0x4d, 0x31, 0x70, 0x47,
-	0xc6, 0x07, 0x80, 0xad, 0x52, 0x88, 0x20, 0x76, 0x94, 0x9b, 0x62, 0xe4, 0xce, 0x26, 0xb3, 0x39,
-	0xdc, 0xfc, 0x7a, 0x4a, 0xcb, 0x8d, 0x10, 0xde, 0x03, 0x9c, 0x44, 0x0f, 0x8e, 0xe9, 0xd1, 0xf1,
-	0xe9, 0x0d, 0x92, 0xfe, 0x03, 0xbe, 0xc0, 0x55, 0x7f, 0x7a, 0x0d, 0x7e, 0x4f, 0x38, 0x2d, 0x4f,
-	0xf9, 0xcb, 0x3f, 0xd9, 0xb3, 0xec, 0xbf, 0xab, 0x7e, 0xdd, 0xe2, 0xfa, 0xf3, 0xac, 0xb7, 0x3a,
-	0x89, 0x55, 0x3e, 0xff, 0x04, 0x00, 0x00, 0xff, 0xff, 0xa4, 0x68, 0x4a, 0x92, 0x60, 0x01, 0x00,
-	0x00,
+	// 639 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x94, 0x54, 0x4f, 0x4f, 0xdb, 0x4e,
+	0x10, 0x95, 0x1d, 0x6c, 0xe2, 0x49, 0x80, 0x1f, 0x0b, 0x8a, 0x4c, 0xf4, 0x6b, 0x6b, 0x59, 0x6d,
+	0x15, 0x71, 0x08, 0x28, 0x3d, 0x73, 0x00, 0x55, 0xed, 0xa1, 0xad, 0x1a, 0xb6, 0xb7, 0x5e, 0xac,
+	0x4d, 0x32, 0x24, 0x46, 0x24, 0x36, 0xbb, 0x1b, 0x08, 0xdf, 0xa6, 0x5f, 0xa2, 0x9f, 0xae, 0x52,
+	0x55, 0x79, 0x76, 0x9d, 0x38, 0xfc, 0x11, 0xed, 0xf1, 0xbd, 0x37, 0x33, 0x3b, 0xf3, 0x66, 0x6c,
+	0x60, 0xb9, 0xcc, 0x74, 0x76, 0x24, 0x71, 0x36, 0x42, 0xd9, 0x25, 0xc0, 0xc0, 0xa0, 0x11, 0x4e,
+	0xb3, 0xf8, 0x97, 0x0b, 0x5b, 0x9c, 0x20, 0xc7, 0xeb, 0x39, 0x2a, 0xcd, 0xde, 0xc2, 0xce, 0x78,
+	0xa8, 0x92, 0x6c, 0xae, 0xf3, 0xb9, 0x4e, 0x06, 0x42, 0x61, 0xe8, 0x44, 0x4e, 0x27, 0xe0, 0x5b,
+	0xe3, 0xa1, 0xfa, 0x4a, 0xec, 0x99, 0x50, 0xc8, 0x0e, 0xa0, 0x9e, 0x0d, 0x2e, 0x93, 0x5c, 0xe8,
+	0x49, 0xe8, 0x52, 0xc0, 0x66, 0x36, 0xb8, 0xec, 0x0b, 0x3d, 0x61, 0x2d, 0xf0, 0x85, 0x52, 0xa8,
+	0x55, 0x58, 0x8b, 0x6a, 0x9d, 0x80, 0x5b, 0xc4, 0xda, 0x50, 0x97, 0x99, 0x16, 0x3a, 0xcd, 0x66,
+	0xe1, 0x46, 0xe4, 0x74, 0x5c, 0xbe, 0xc4, 0xec, 0x25, 0x40, 0xaa, 0x51, 0x12, 0x50, 0xa1, 0x17,
+	0x39, 0x1d, 0x8f, 0x57, 0x18, 0xb6, 0x0f, 0xde, 0x6d, 0x3a, 0xd2, 0x93, 0xd0, 0x27, 0xc9, 0x80,
+	0xe2, 0xa5, 0x09, 0xa6, 0xe3, 0x89, 0x0e, 0x37, 0x89, 0xb6, 0x88, 0x1d, 0xc2, 0xae, 0x12, 0xd3,
+	0xfc, 0x0a, 0x55, 0x92, 0xa3, 0x4c, 0xf2, 0x74, 0x81, 0x57, 0x61, 0x9d, 0x42, 0x76, 0xac, 0xd0,
+	0x47, 0xd9, 0x2f, 0x68, 0xf6, 0x02, 0x40, 0x0d, 0x71, 0x86, 0x66, 0x94, 0x80, 0x46, 0x09, 0x88,
+	0xa1, 0x61, 0x0e, 0xc1, 0x97, 0x38, 0x2e, 0x5a, 0x86, 0xc8, 0xe9, 0x34, 0x7a, 0xac, 0xbb, 0xb2,
+	0xaf, 0xcb, 0x49, 0xe1, 0x36, 0x82, 0x75, 0x61, 0x2f, 0x97, 0x78, 0x93, 0xe2, 0xed, 0x9a, 0x7f,
+	0x0d, 0xaa, 0xb9, 0x6b, 0xa5, 0x95, 0x87, 0x31, 0x07, 0xdf, 0x54, 0x60, 0x4d, 0x70, 0x16, 0xe4,
+	0xb3, 0xc7, 0x9d, 0x45, 0x81, 0xee, 0xc8, 0x54, 0x8f, 0x3b, 0x77, 0xab, 0xd1, 0x6b, 0x8f, 0x8f,
+	0xbe, 0x51, 0x1d, 0x3d, 0x3e, 0x82, 0xed, 0x72, 0xa1, 0x2a, 0xcf, 0x66, 0x0a, 0x8b, 0x01, 0x57,
+	0x1b, 0xb5, 0xcb, 0x0c, 0x96, 0xcb, 0x8c, 0x87, 0x65, 0x42, 0x5f, 0x66, 0x63, 0x89, 0x4a, 0xb1,
+	0xff, 0x21, 0x58, 0x3a, 0x6f, 0x9b, 0x5a, 0x11, 0xf7, 0xca, 0xb9, 0xf7, 0xca, 0x15, 0xdd, 0x5e,
+	0xa4, 0x33, 0x71, 0x45, 0xdd, 0xd6, 0xb9, 0x01, 0xf1, 0x6f, 0x07, 0xf6, 0xcc, 0x2b, 0x1f, 0xa4,
+	0x98, 0xa2, 0x2a, 0xaf, 0xed, 0x08, 0x3c, 0xb2, 0x9a, 0x9e, 0x69, 0xf4, 0x0e, 0xd6, 0xcd, 0xad,
+	0xdc, 0x25, 0x37, 0x71, 0xec, 0x04, 0xfc, 0x0b, 0xaa, 0x10, 0xba, 0x51, 0xad, 0xd3, 0xe8, 0xbd,
+	0x79, 0x98, 0xb1, 0xf6, 0x42, 0x97, 0x10, 0xb7, 0x49, 0xed, 0x5b, 0xf0, 0x88, 0xf8, 0xeb, 0x33,
+	0xaf, 0xde, 0xac, 0x7b, 0xef, 0x66, 0x57, 0xa7, 0x51, 0x7b, 0xee, 0x34, 0xe2, 0x4f, 0xb0, 0xbf,
+	0xde, 0x9d, 0x5d, 0x4e, 0x61, 0x57, 0xc1, 0x58, 0x9f, 0x0d, 0x78, 0xc6, 0xe3, 0xf8, 0x3f, 0xd8,
+	0xfe, 0x88, 0xfa, 0x73, 0x26, 0x46, 0x76, 0xca, 0xf8, 0x87, 0x03, 0x60, 0xea, 0x17, 0x2c, 0x7d,
+	0x81, 0x43, 0x9d, 0xde, 0x94, 0x65, 0x2d, 0x2a, 0xea, 0x4e, 0xc5, 0x22, 0xb1, 0x9a, 0xb9, 0xb0,
+	0x60, 0x2a, 0x16, 0xa7, 0x46, 0x6e, 0x81, 0x7f, 0x3d, 0xc7, 0x39, 0x8e, 0xec, 0xa9, 0x59, 0x54,
+	0xa6, 0x59, 0x6d, 0x63, 0x99, 0x76, 0x6e, 0xe4, 0xd7, 0xb0, 0x2d, 0x51, 0xcb, 0xbb, 0x44, 0x5c,
+	0x68, 0x94, 0xc9, 0xd4, 0x7c, 0xbf, 0x35, 0xde, 0x24, 0xf6, 0xb4, 0x20, 0xbf, 0xa8, 0xde, 0x4f,
+	0x17, 0x7c, 0xd3, 0x22, 0x7b, 0x0f, 0x8d, 0x8a, 0x19, 0xec, 0xe9, 0xad, 0xb7, 0xdb, 0x8f, 0x49,
+	0xd6, 0xba, 0x6f, 0xd0, 0xac, 0x5a, 0xca, 0x5e, 0x3d, 0x73, 0x0a, 0xed, 0xe8, 0xe9, 0x00, 0x53,
+	0xf2, 0xd8, 0x61, 0xe7, 0xd0, 0xaa, 0x28, 0xe5, 0x27, 0x91, 0xde, 0xfc, 0x6b, 0x97, 0x65, 0xe6,
+	0xb1, 0xc3, 0x4e, 0x60, 0xd3, 0x6e, 0x8b, 0xad, 0x05, 0xae, 0xaf, 0xb0, 0xdd, 0x7a, 0x58, 0xa4,
+	0x90, 0xcf, 0x9a, 0xdf, 0x2b, 0x3f, 0xec, 0x81, 0x4f, 0xff, 0xf0, 0x77, 0x7f, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0x01, 0x00, 0x00, 0xff, 0xff, 0xad, 0x23, 0x2d, 0x58, 0xd9, 0x05, 0x00, 0x00,
 }
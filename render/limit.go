@@ -0,0 +1,178 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	pb "github.com/GoogleCloudPlatform/gifinator/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+/**
+ * A render keeps every CPU of the pod busy, so running many at once only
+ * makes each of them slower. Every render RPC takes one of maxActive slots
+ * for as long as it runs, and up to maxQueued more wait for a slot in the
+ * order they arrived. Anything beyond that is refused straight away with
+ * RESOURCE_EXHAUSTED, and a retry-after-ms trailer estimating when there will
+ * be room, worked out from how long renders have been taking.
+ *
+ * The limit is applied by interceptors, so that it covers every render RPC
+ * but not GetLoad, which callers use to pick the least busy replica.
+ */
+
+const getLoadMethod = "/renderdemo.Render/GetLoad"
+
+// Settings used when MAX_CONCURRENT_RENDERS and MAX_QUEUED_RENDERS are unset.
+const (
+	defaultMaxActiveRenders = 1
+	defaultMaxQueuedRenders = 4
+)
+
+type renderLimiter struct {
+	slots     chan struct{}
+	maxQueued int32
+
+	mu      sync.Mutex
+	queued  int32
+	average time.Duration // moving average of how long a render holds a slot
+}
+
+func newRenderLimiter(maxActive, maxQueued int32) *renderLimiter {
+	return &renderLimiter{
+		slots:     make(chan struct{}, maxActive),
+		maxQueued: maxQueued,
+	}
+}
+
+// acquire waits for a slot, and returns a function to give it back. It fails
+// with RESOURCE_EXHAUSTED if too many renders are waiting already, in which
+// case retryAfter is how long the caller should wait before trying again.
+func (l *renderLimiter) acquire(ctx context.Context) (release func(), retryAfter time.Duration, err error) {
+	select {
+	case l.slots <- struct{}{}:
+		return l.releaser(), 0, nil
+	default:
+	}
+
+	l.mu.Lock()
+	if l.queued >= l.maxQueued {
+		retryAfter = l.retryAfterLocked()
+		l.mu.Unlock()
+		return nil, retryAfter, grpc.Errorf(codes.ResourceExhausted, "render queue is full, retry in %v", retryAfter)
+	}
+	l.queued++
+	l.mu.Unlock()
+	defer func() {
+		l.mu.Lock()
+		l.queued--
+		l.mu.Unlock()
+	}()
+
+	select {
+	case l.slots <- struct{}{}:
+		return l.releaser(), 0, nil
+	case <-ctx.Done():
+		return nil, 0, renderError(ctx, ctx.Err())
+	}
+}
+
+func (l *renderLimiter) releaser() func() {
+	start := time.Now()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			took := time.Since(start)
+			l.mu.Lock()
+			if l.average == 0 {
+				l.average = took
+			} else {
+				l.average += (took - l.average) / 8
+			}
+			l.mu.Unlock()
+			<-l.slots
+		})
+	}
+}
+
+// retryAfterLocked estimates how long it will be until a render sent now
+// would be accepted: long enough for the renders running and waiting to get
+// through a slot each. l.mu must be held.
+func (l *renderLimiter) retryAfterLocked() time.Duration {
+	average := l.average
+	if average == 0 {
+		average = time.Second
+	}
+	return average * time.Duration(l.queued+1) / time.Duration(cap(l.slots))
+}
+
+// load reports how busy the limiter is.
+func (l *renderLimiter) load() *pb.RenderLoad {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	load := &pb.RenderLoad{
+		Active:    int32(len(l.slots)),
+		MaxActive: int32(cap(l.slots)),
+		Queued:    l.queued,
+		MaxQueued: l.maxQueued,
+	}
+	if len(l.slots) == cap(l.slots) && l.queued >= l.maxQueued {
+		// A render sent now would be refused
+		load.RetryAfterMs = int64(l.retryAfterLocked() / time.Millisecond)
+	}
+	return load
+}
+
+func retryAfterTrailer(retryAfter time.Duration) metadata.MD {
+	return metadata.Pairs("retry-after-ms", strconv.FormatInt(int64(retryAfter/time.Millisecond), 10))
+}
+
+func (l *renderLimiter) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if info.FullMethod == getLoadMethod {
+		return handler(ctx, req)
+	}
+	release, retryAfter, err := l.acquire(ctx)
+	if err != nil {
+		if retryAfter > 0 {
+			grpc.SetTrailer(ctx, retryAfterTrailer(retryAfter))
+		}
+		return nil, err
+	}
+	defer release()
+	return handler(ctx, req)
+}
+
+func (l *renderLimiter) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	release, retryAfter, err := l.acquire(ss.Context())
+	if err != nil {
+		if retryAfter > 0 {
+			ss.SetTrailer(retryAfterTrailer(retryAfter))
+		}
+		return err
+	}
+	defer release()
+	return handler(srv, ss)
+}
+
+func (server) GetLoad(ctx context.Context, req *pb.GetLoadRequest) (*pb.RenderLoad, error) {
+	return renderLimits.load(), nil
+}
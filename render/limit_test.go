@@ -0,0 +1,186 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	pb "github.com/GoogleCloudPlatform/gifinator/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// waitForQueued waits until n acquire calls are waiting on l.
+func waitForQueued(t *testing.T, l *renderLimiter, n int32) {
+	deadline := time.Now().Add(5 * time.Second)
+	for l.load().Queued != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d renders queued, want %d", l.load().Queued, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRenderLimiter(t *testing.T) {
+	tests := []struct {
+		desc               string
+		maxActive          int32
+		maxQueued          int32
+		average            time.Duration
+		running, queued    int32
+		want               codes.Code
+		wantRetryAfter     time.Duration
+		wantRetryAfterLoad int64
+	}{
+		{"idle", 1, 4, 0, 0, 0, codes.OK, 0, 0},
+		{"free slot", 2, 0, 0, 1, 0, codes.OK, 0, 0},
+		{"no queue", 1, 0, 0, 1, 0, codes.ResourceExhausted, time.Second, 1000},
+		{"queue full", 1, 2, 0, 1, 2, codes.ResourceExhausted, 3 * time.Second, 3000},
+		{"queue full, measured", 1, 2, 10 * time.Second, 1, 2, codes.ResourceExhausted, 30 * time.Second, 30000},
+		{"queue full, several slots", 2, 2, 10 * time.Second, 2, 2, codes.ResourceExhausted, 15 * time.Second, 15000},
+	}
+	for _, tt := range tests {
+		l := newRenderLimiter(tt.maxActive, tt.maxQueued)
+		l.average = tt.average
+		ctx, cancel := context.WithCancel(context.Background())
+
+		var releases []func()
+		for i := int32(0); i < tt.running; i++ {
+			release, _, err := l.acquire(ctx)
+			if err != nil {
+				t.Fatalf("%s: acquire %d: %v", tt.desc, i, err)
+			}
+			releases = append(releases, release)
+		}
+		for i := int32(0); i < tt.queued; i++ {
+			go l.acquire(ctx)
+		}
+		waitForQueued(t, l, tt.queued)
+
+		wantLoad := &pb.RenderLoad{
+			Active:       tt.running,
+			MaxActive:    tt.maxActive,
+			Queued:       tt.queued,
+			MaxQueued:    tt.maxQueued,
+			RetryAfterMs: tt.wantRetryAfterLoad,
+		}
+		if got := l.load(); !reflect.DeepEqual(got, wantLoad) {
+			t.Errorf("%s: load = %+v, want %+v", tt.desc, got, wantLoad)
+		}
+
+		release, retryAfter, err := l.acquire(ctx)
+		if got := grpc.Code(err); got != tt.want {
+			t.Errorf("%s: acquire = %v, want %v", tt.desc, err, tt.want)
+		}
+		if retryAfter != tt.wantRetryAfter {
+			t.Errorf("%s: acquire retry after %v, want %v", tt.desc, retryAfter, tt.wantRetryAfter)
+		}
+		if release != nil {
+			release()
+		}
+		// Stop the waiters, then free the slots
+		cancel()
+		waitForQueued(t, l, 0)
+		for _, release := range releases {
+			release()
+		}
+	}
+}
+
+func TestRenderLimiterQueue(t *testing.T) {
+	l := newRenderLimiter(1, 1)
+	release, _, err := l.acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acquired := make(chan error)
+	go func() {
+		release, _, err := l.acquire(context.Background())
+		if release != nil {
+			release()
+		}
+		acquired <- err
+	}()
+	waitForQueued(t, l, 1)
+	select {
+	case err := <-acquired:
+		t.Fatalf("queued render started while the slot was taken: %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	// Releasing twice must not free two slots
+	release()
+	release()
+	if err := <-acquired; err != nil {
+		t.Errorf("queued render: %v", err)
+	}
+	if l.average <= 0 {
+		t.Errorf("average render time is %v after a render", l.average)
+	}
+	if got := len(l.slots); got != 0 {
+		t.Errorf("%d slots taken after every render finished", got)
+	}
+}
+
+func TestRenderLimiterCancel(t *testing.T) {
+	l := newRenderLimiter(1, 1)
+	release, _, err := l.acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, _, err = l.acquire(ctx)
+	if got := grpc.Code(err); got != codes.DeadlineExceeded {
+		t.Errorf("acquire past the deadline = %v, want %v", err, codes.DeadlineExceeded)
+	}
+	if got := l.load().Queued; got != 0 {
+		t.Errorf("%d renders queued after the waiter gave up", got)
+	}
+}
+
+func TestRenderLimiterGetLoad(t *testing.T) {
+	l := newRenderLimiter(1, 0)
+	release, _, err := l.acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "handled", nil
+	}
+	tests := []struct {
+		method string
+		want   codes.Code
+	}{
+		{getLoadMethod, codes.OK},
+		{"/renderdemo.Render/RenderFrame", codes.ResourceExhausted},
+	}
+	for _, tt := range tests {
+		_, err := l.unaryInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+		if got := grpc.Code(err); got != tt.want {
+			t.Errorf("%s with every slot taken = %v, want %v", tt.method, err, tt.want)
+		}
+	}
+}
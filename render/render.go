@@ -18,151 +18,463 @@ package main
 
 import (
 	"fmt"
+	"image"
+	"io"
+	"io/ioutil"
 	"log"
 	"net"
 	"os"
+	"path"
+	"path/filepath"
 	"strconv"
-	"math/rand"
-	"io/ioutil"
 
-	"cloud.google.com/go/storage"
+	"github.com/GoogleCloudPlatform/gifinator/internal/blobstore"
+	"github.com/GoogleCloudPlatform/gifinator/internal/diskcache"
 	"github.com/GoogleCloudPlatform/gifinator/internal/gcsref"
+	"github.com/GoogleCloudPlatform/gifinator/internal/scene"
 	pb "github.com/GoogleCloudPlatform/gifinator/proto"
+	"github.com/fogleman/pt/pt"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
-	"github.com/fogleman/pt/pt"
+	"google.golang.org/grpc/codes"
 )
 
 type server struct{}
 
 var (
-	gcsClient *storage.Client
-	gcsCacheDir string
+	blobStore blobstore.BlobStore
+
+	// scratchDir holds a working directory for each request in flight. Set
+	// from RENDER_SCRATCH_DIR.
+	scratchDir string
+
+	// renderCache keeps the objects that renders download, so that meshes
+	// and textures shared between frames and jobs are fetched once per node.
+	// Sized by RENDER_CACHE_MB and kept in RENDER_CACHE_DIR.
+	renderCache *diskcache.Cache
+
+	// renderLimits caps how many renders run at once and how many may wait,
+	// set from MAX_CONCURRENT_RENDERS and MAX_QUEUED_RENDERS (see limit.go).
+	renderLimits *renderLimiter
+
+	// Caps on what a single request may ask for, so that no one request can
+	// tie up a render pod for hours. Set from MAX_RENDER_WIDTH,
+	// MAX_RENDER_HEIGHT, MAX_SAMPLES_PER_PIXEL and MAX_ITERATIONS.
+	maxWidth           int32 = 1024
+	maxHeight          int32 = 1024
+	maxSamplesPerPixel int32 = 64
+	maxIterations      int32 = 4
+)
+
+// Settings used for requests that leave them unset, which match what every
+// frame was rendered with before they could be chosen.
+const (
+	defaultWidth           = 300
+	defaultHeight          = 300
+	defaultSamplesPerPixel = 16
+	defaultIterations      = 1
+
+	defaultRenderCacheMB = 1024
+
+	// maxTiledSize is the largest image, along each side, that a request may
+	// render a region of. The region itself must fit in maxWidth by
+	// maxHeight.
+	maxTiledSize = 16384
 )
 
-func cacheGcsObject(ctx context.Context, obj gcsref.Object) (string, error) {
-	// TODO(jessup) This will have collisions! Fix.
-	localFilepath := gcsCacheDir+"/"+obj.Name
+// checkRenderSettings fills in the defaults for a request, and rejects it if
+// it asks for more than this deployment allows.
+func checkRenderSettings(req *pb.RenderRequest) error {
+	if req.Width == 0 {
+		req.Width = defaultWidth
+	}
+	if req.Height == 0 {
+		req.Height = defaultHeight
+	}
+	if req.SamplesPerPixel == 0 {
+		req.SamplesPerPixel = defaultSamplesPerPixel
+	}
+	if req.Iterations == 0 {
+		req.Iterations = defaultIterations
+	}
+	if req.SamplesPerPixel < 1 || req.SamplesPerPixel > maxSamplesPerPixel {
+		return grpc.Errorf(codes.InvalidArgument, "samples per pixel must be between 1 and %d", maxSamplesPerPixel)
+	}
+	if req.Iterations < 1 || req.Iterations > maxIterations {
+		return grpc.Errorf(codes.InvalidArgument, "iterations must be between 1 and %d", maxIterations)
+	}
+	if req.Region == nil {
+		if req.Width < 1 || req.Width > maxWidth || req.Height < 1 || req.Height > maxHeight {
+			return grpc.Errorf(codes.InvalidArgument, "image size %dx%d is outside the limit of %dx%d", req.Width, req.Height, maxWidth, maxHeight)
+		}
+		return nil
+	}
+	// Only the region is traced, so it is the region that has to fit in the
+	// size limit, which lets large frames be rendered a tile at a time
+	if req.Width < 1 || req.Width > maxTiledSize || req.Height < 1 || req.Height > maxTiledSize {
+		return grpc.Errorf(codes.InvalidArgument, "image size %dx%d is outside the limit of %dx%d for tiles", req.Width, req.Height, maxTiledSize, maxTiledSize)
+	}
+	err := checkRegion(req.Region, req.Width, req.Height)
+	if err != nil {
+		return err
+	}
+	if req.Region.Width > maxWidth || req.Region.Height > maxHeight {
+		return grpc.Errorf(codes.InvalidArgument, "region size %dx%d is outside the limit of %dx%d", req.Region.Width, req.Region.Height, maxWidth, maxHeight)
+	}
+	return nil
+}
+
+// checkRegion rejects regions that are empty or reach outside the image.
+func checkRegion(r *pb.Region, width int32, height int32) error {
+	if r == nil {
+		return nil
+	}
+	if r.X < 0 || r.Y < 0 || r.Width < 1 || r.Height < 1 || r.X+r.Width > width || r.Y+r.Height > height {
+		return grpc.Errorf(codes.InvalidArgument, "region %dx%d at %d,%d is outside the %dx%d image", r.Width, r.Height, r.X, r.Y, width, height)
+	}
+	return nil
+}
 
-  // TODO(jessup) Check if file exists before pulling from disk
-	fmt.Fprintf(os.Stdout, "DEBUG %s: %s", string(obj.Bucket), obj.Name)
+// cacheObject makes a copy of an object available under its own name in a
+// request's working directory, which is where the renderer looks for the
+// materials and textures that a mesh names. The object is only downloaded if
+// this node has not already cached the same version of it.
+func cacheObject(ctx context.Context, workDir string, obj gcsref.Ref) (string, error) {
+	localFilepath := filepath.Join(workDir, path.Base(obj.Name))
 
-	rc, err := gcsClient.Bucket(string(obj.Bucket)).Object(obj.Name).NewReader(ctx)
+	attrs, err := blobStore.Stat(ctx, obj)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error creating reader for %s/%s %v", string(obj.Bucket), obj.Name, err)
+		fmt.Fprintf(os.Stderr, "error looking up %v: %v\n", obj, err)
 		return "", err
 	}
-	defer rc.Close()
-
-	// TODO(jessup) Fix this to read incrementally and not store files in memory
-	slurp, err := ioutil.ReadAll(rc)
-  if err != nil {
-    fmt.Fprintf(os.Stderr, "readFile: unable to read data from bucket %q, file %q: %v", obj.Bucket, localFilepath, err)
-    return "", err
-  }
-  fmt.Fprintf(os.Stdout, "writing file %q\n", localFilepath)
-  err = ioutil.WriteFile(localFilepath, slurp, 0777)
-  if err != nil {
-		fmt.Fprintf(os.Stderr, "error while writing file %q: %v\n", localFilepath, err)
+	cachedPath, release, err := renderCache.Get(ctx, obj.String()+"#"+attrs.Version, func(w io.Writer) error {
+		fmt.Fprintf(os.Stdout, "downloading %v\n", obj)
+		return blobstore.Download(ctx, blobStore, attrs, w)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error caching %v: %v\n", obj, err)
 		return "", err
 	}
+	defer release()
 
+	err = linkFile(cachedPath, localFilepath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error while writing file %q: %v\n", localFilepath, err)
+		return "", err
+	}
 	return localFilepath, nil
 }
 
-func renderImage(objectPath string, rotation float64, iterations int32) (string, error) {
-	scene := pt.Scene{}
-
-	// create materials
-  objMat := pt.GlossyMaterial(pt.Black, 1.2, pt.Radians(30))
-	wall := pt.GlossyMaterial(pt.HexColor(0xFCFAE1), 1.5, pt.Radians(10))
-	light := pt.LightMaterial(pt.White, 80)
+// linkFile makes dst a hard link to src, copying it if the two are on
+// different filesystems. The link survives src being evicted from the cache.
+func linkFile(src, dst string) error {
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+	return copyFile(src, dst)
+}
 
-	// add walls and lights
-	scene.Add(pt.NewCube(pt.V(-10, -1, -10), pt.V(-2, 10, 10), wall))
-	scene.Add(pt.NewCube(pt.V(-10, -1, -10), pt.V(10, 0, 10), wall))
-	scene.Add(pt.NewSphere(pt.V(4, 10, 1), 1, light))
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dst)
+	}
+	return err
+}
 
-	// load and transform gopher mesh
-	mesh, err := pt.LoadOBJ(objectPath, objMat)
+// renderImage renders the object turned by rotation, or just the given region
+// of the image, and saves it in workDir. It gives up between rows once ctx
+// is done.
+func renderImage(ctx context.Context, desc *scene.Description, workDir string, mesh *pt.Mesh, rotation float64, iterations int32, width int32, height int32, samplesPerPixel int32, region *pb.Region) (string, error) {
+	t, err := buildTracer(desc, mesh, rotation, width, height, samplesPerPixel, region)
 	if err != nil {
 		return "", err
 	}
+	if err := t.trace(ctx, int(iterations)); err != nil {
+		return "", err
+	}
+
+	imagePath := filepath.Join(workDir, "final_img.png")
+	if err := pt.SavePNG(imagePath, t.image()); err != nil {
+		return "", err
+	}
+	return imagePath, nil
+}
 
-	mesh.Transform(pt.Rotate(pt.V(0, 1, 0), pt.Radians(-10)))
-	mesh.SmoothNormals()
-	mesh.FitInside(pt.Box{pt.V(-1, 0, -1), pt.V(1, 2, 1)}, pt.V(0.5, 0, 0.5))
-	mesh.Transform(pt.Rotate(pt.V(0, 1, 0), pt.Radians(rotation)))
-	scene.Add(mesh)
+func loadScene(ctx context.Context, workDir string, scenePath string) (*scene.Description, error) {
+	sceneRef, err := gcsref.ParseRef(scenePath)
+	if err != nil {
+		return nil, err
+	}
+	sceneFilepath, err := cacheObject(ctx, workDir, sceneRef)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(sceneFilepath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	desc, err := scene.Parse(f)
+	if err != nil {
+		return nil, grpc.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	return desc, nil
+}
 
-	// position camera
-	camera := pt.LookAt(pt.V(4, 1, 0), pt.V(0, 0.9, 0), pt.V(0, 1, 0), 30)
+func (server) RenderFrame(ctx context.Context, req *pb.RenderRequest) (_ *pb.RenderResponse, err error) {
+	defer func() { err = renderError(ctx, err) }()
+	fmt.Fprintf(os.Stdout, "starting render job - object: %s, angle: %f\n", req.ObjPath, req.Rotation)
+	if err := checkRenderSettings(req); err != nil {
+		return nil, err
+	}
 
-	// render the scene
-	sampler := pt.NewSampler(16, 16)
-	renderer := pt.NewRenderer(&scene, &camera, sampler, 300, 300)
+	// Give the request a directory of its own, so that its files cannot
+	// collide with those of concurrent requests. It is removed however the
+	// request ends, including when it is cancelled part way through
+	workDir, err := ioutil.TempDir(scratchDir, "render-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(workDir)
 
-  // TODO(jessup) Fix this for better entropy
-	imagePath := os.TempDir() + "/final_img_itr_%d_" + strconv.FormatInt(int64(rand.Intn(10000)), 16) + ".png"
-	renderer.IterativeRender(imagePath, int(iterations))
+	desc, mesh, err := prepareRender(ctx, workDir, req)
+	if err != nil {
+		return nil, err
+	}
+	gcsPath, err := renderFrame(ctx, workDir, req, desc, mesh, req.GcsOutputBase, req.Rotation, req.Region)
+	if err != nil {
+		return nil, err
+	}
 
-	return fmt.Sprintf(imagePath, iterations), nil
+	response := pb.RenderResponse{GcsOutput: gcsPath}
+	return &response, nil
 }
 
+func (server) RenderFrames(req *pb.RenderFramesRequest, stream pb.Render_RenderFramesServer) (err error) {
+	ctx := stream.Context()
+	defer func() { err = renderError(ctx, err) }()
+	if req.Scene == nil || len(req.Frames) == 0 {
+		return grpc.Errorf(codes.InvalidArgument, "a scene and at least one frame are required")
+	}
+	fmt.Fprintf(os.Stdout, "starting render batch - object: %s, frames: %d\n", req.Scene.ObjPath, len(req.Frames))
+	if err := checkRenderSettings(req.Scene); err != nil {
+		return err
+	}
 
-func (server) RenderFrame(ctx context.Context, req *pb.RenderRequest) (*pb.RenderResponse, error) {
-	gcsClient, _ = storage.NewClient(ctx)
+	workDir, err := ioutil.TempDir(scratchDir, "render-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
 
-	fmt.Fprintf(os.Stdout, "starting render job - object: %s, angle: %f\n", req.ObjPath, req.Rotation)
+	// Everything but the rotation is shared, so load it all once
+	desc, mesh, err := prepareRender(ctx, workDir, req.Scene)
+	if err != nil {
+		return err
+	}
+	for _, frame := range req.Frames {
+		if err := checkRegion(frame.Region, req.Scene.Width, req.Scene.Height); err != nil {
+			return err
+		}
+	}
+	for i, frame := range req.Frames {
+		gcsPath, err := renderFrame(ctx, workDir, req.Scene, desc, mesh, frame.GcsOutputBase, frame.Rotation, frame.Region)
+		if err != nil {
+			return err
+		}
+		err = stream.Send(&pb.RenderFramesResponse{Frame: int32(i), GcsOutput: gcsPath})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (server) RenderFrameProgressive(req *pb.RenderRequest, stream pb.Render_RenderFrameProgressiveServer) (err error) {
+	ctx := stream.Context()
+	defer func() { err = renderError(ctx, err) }()
+	fmt.Fprintf(os.Stdout, "starting progressive render job - object: %s, angle: %f\n", req.ObjPath, req.Rotation)
+	if err := checkRenderSettings(req); err != nil {
+		return err
+	}
+	if req.Region != nil {
+		return grpc.Errorf(codes.InvalidArgument, "regions cannot be rendered progressively")
+	}
+	if req.PreviewOutputBase == "" {
+		return grpc.Errorf(codes.InvalidArgument, "preview_output_base is required")
+	}
+
+	workDir, err := ioutil.TempDir(scratchDir, "render-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+
+	desc, mesh, err := prepareRender(ctx, workDir, req)
+	if err != nil {
+		return err
+	}
+	t, err := buildTracer(desc, mesh, float64(req.Rotation), req.Width, req.Height, req.SamplesPerPixel, nil)
+	if err != nil {
+		return err
+	}
+
+	send := func(i int32, img image.Image, final bool) error {
+		outputBase := req.PreviewOutputBase
+		if final {
+			outputBase = req.GcsOutputBase
+		}
+		imgPath := filepath.Join(workDir, fmt.Sprintf("final_img_itr_%d.png", i))
+		if err := pt.SavePNG(imgPath, img); err != nil {
+			return err
+		}
+		gcsPath, err := uploadImage(ctx, imgPath, outputBase, req.Rotation)
+		if err != nil {
+			return err
+		}
+		return stream.Send(&pb.RenderProgress{Iteration: i, GcsOutput: gcsPath, Final: final})
+	}
+
+	if req.Iterations == 1 {
+		// There is no earlier iteration to show, so start with a rough
+		// preview that takes a single sample where the frame takes
+		// samples_per_pixel. It costs about 1/samples_per_pixel of the frame
+		preview := newTracer(t.scene, t.camera, pt.NewSampler(1, 16), t.width, t.height, t.region)
+		if err := preview.trace(ctx, 1); err != nil {
+			return err
+		}
+		if err := send(1, preview.image(), false); err != nil {
+			return err
+		}
+	}
+	// Each pass adds another iteration's samples to the image
+	for i := int32(1); i <= req.Iterations; i++ {
+		if err := t.trace(ctx, 1); err != nil {
+			return err
+		}
+		if err := send(i, t.image(), i == req.Iterations); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-  // Load main object file
-	objGcsObj, _ := gcsref.Parse(req.ObjPath)
-  objFilepath, err := cacheGcsObject(ctx, objGcsObj)
+// renderError turns the error that ended a request into the one to return.
+// Once the caller has gone away or its deadline has passed, that is why the
+// request stopped, whatever error it ran into along the way.
+func renderError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	switch ctx.Err() {
+	case context.Canceled:
+		fmt.Fprintf(os.Stdout, "render cancelled: %v\n", err)
+		return grpc.Errorf(codes.Canceled, "render cancelled")
+	case context.DeadlineExceeded:
+		fmt.Fprintf(os.Stdout, "render deadline exceeded: %v\n", err)
+		return grpc.Errorf(codes.DeadlineExceeded, "render deadline exceeded")
+	}
+	return err
+}
+
+// prepareRender fetches everything that a request needs into workDir, and
+// loads its scene and object.
+func prepareRender(ctx context.Context, workDir string, req *pb.RenderRequest) (*scene.Description, *pt.Mesh, error) {
+	// Load main object file
+	objRef, err := gcsref.ParseRef(req.ObjPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	objFilepath, err := cacheObject(ctx, workDir, objRef)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error caching %s, err: %v\n", req.ObjPath, err)
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Load the assets
-  for _,element := range req.Assets {
-		assetGcsObj, _ := gcsref.Parse(element)
-	  _, err := cacheGcsObject(ctx, assetGcsObj)
+	for _, element := range req.Assets {
+		assetRef, err := gcsref.ParseRef(element)
+		if err != nil {
+			return nil, nil, err
+		}
+		_, err = cacheObject(ctx, workDir, assetRef)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "error caching %s, err: %v\n", req.ObjPath, err)
-			return nil, err
+			return nil, nil, err
+		}
+	}
+
+	// Load the scene to put the object in
+	desc := scene.Default()
+	if req.ScenePath != "" {
+		desc, err = loadScene(ctx, workDir, req.ScenePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error loading scene %s, err: %v\n", req.ScenePath, err)
+			return nil, nil, err
 		}
 	}
 
-  // Create and render a scene seeded with the object we loaded
-	fmt.Fprintf(os.Stdout, "starting actual render - object: %s, angle: %f\n", req.ObjPath, req.Rotation)
-  imgPath, err := renderImage(objFilepath, float64(req.Rotation), req.Iterations)
+	mesh, err := desc.LoadMesh(objFilepath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading %s, err: %v\n", req.ObjPath, err)
+		return nil, nil, err
+	}
+	return desc, mesh, nil
+}
 
-	fmt.Fprintf(os.Stdout, "finshed actual render - object: %s, angle: %f\n", req.ObjPath, req.Rotation)
+// renderFrame renders the object turned by rotation, or just the given region
+// of it, and writes the image to the blob store next to outputBase. It
+// returns the path of the image.
+func renderFrame(ctx context.Context, workDir string, req *pb.RenderRequest, desc *scene.Description, mesh *pt.Mesh, outputBase string, rotation float32, region *pb.Region) (string, error) {
+	// Create and render a scene seeded with the object we loaded
+	fmt.Fprintf(os.Stdout, "starting actual render - object: %s, angle: %f\n", req.ObjPath, rotation)
+	imgPath, err := renderImage(ctx, desc, workDir, mesh, float64(rotation), req.Iterations,
+		req.Width, req.Height, req.SamplesPerPixel, region)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error rendering %s, err: %v\n", req.ObjPath, err)
+		return "", err
+	}
 
-	// Save in GCS
-	gcsPath := fmt.Sprintf("%s.image_%.0frad.png", req.GcsOutputBase, req.Rotation)
-	gcsFinalImageObj, err := gcsref.Parse(gcsPath)
+	fmt.Fprintf(os.Stdout, "finshed actual render - object: %s, angle: %f\n", req.ObjPath, rotation)
+	return uploadImage(ctx, imgPath, outputBase, rotation)
+}
 
-	wc := gcsClient.Bucket(string(gcsFinalImageObj.Bucket)).Object(gcsFinalImageObj.Name).NewWriter(ctx)
-  defer wc.Close()
+// uploadImage writes a rendered image to the blob store next to outputBase,
+// and returns its path.
+func uploadImage(ctx context.Context, imgPath string, outputBase string, rotation float32) (string, error) {
+	gcsPath := fmt.Sprintf("%s.image_%.0frad.png", outputBase, rotation)
+	finalImageRef, err := gcsref.ParseRef(gcsPath)
+	if err != nil {
+		return "", err
+	}
 
-	wc.ObjectAttrs.ContentType = "image/png"
-	fmt.Fprintf(os.Stdout, "starting writing frame: %s from %s, frame: %f\n", gcsPath, imgPath, req.Rotation)
+	fmt.Fprintf(os.Stdout, "starting writing frame: %s from %s, frame: %f\n", gcsPath, imgPath, rotation)
 
-	// TODO(jessup) Do this iteratively to save memory
-  contents, err := ioutil.ReadFile(imgPath)
+	imgFile, err := os.Open(imgPath)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error reading file %s, err: %v\n", imgPath, err)
-		return nil, err
+		return "", err
 	}
+	defer imgFile.Close()
 
-	if _, err := wc.Write(contents); err != nil {
-		fmt.Fprintf(os.Stderr, "error writing object %s, err: %v\n", gcsFinalImageObj.Name, err)
-		return nil, err
+	if err := blobstore.Upload(ctx, blobStore, finalImageRef, imgFile, "image/png"); err != nil {
+		fmt.Fprintf(os.Stderr, "error writing object %v, err: %v\n", finalImageRef, err)
+		return "", err
 	}
-
-	response := pb.RenderResponse{GcsOutput: gcsPath}
-	return &response, nil
+	return gcsPath, nil
 }
 
 func main() {
@@ -178,9 +490,55 @@ func main() {
 		log.Fatalf("listen failed: %v", err)
 		return
 	}
-	gcsCacheDir	= os.TempDir()
+	scratchDir = os.Getenv("RENDER_SCRATCH_DIR")
+	if scratchDir == "" {
+		scratchDir = os.TempDir()
+	}
+	if err := os.MkdirAll(scratchDir, 0755); err != nil {
+		log.Fatalf("cannot create scratch directory: %v", err)
+	}
+	for env, max := range map[string]*int32{
+		"MAX_RENDER_WIDTH":      &maxWidth,
+		"MAX_RENDER_HEIGHT":     &maxHeight,
+		"MAX_SAMPLES_PER_PIXEL": &maxSamplesPerPixel,
+		"MAX_ITERATIONS":        &maxIterations,
+	} {
+		if n, err := strconv.Atoi(os.Getenv(env)); err == nil && n > 0 {
+			*max = int32(n)
+		}
+	}
+
+	blobStore, err = blobstore.Open(context.Background(), blobstore.ConfigFromEnv())
+	if err != nil {
+		log.Fatalf("cannot open blob store: %v", err)
+	}
+
+	cacheDir := os.Getenv("RENDER_CACHE_DIR")
+	if cacheDir == "" {
+		cacheDir = filepath.Join(os.TempDir(), "gifinator-cache")
+	}
+	cacheMB := defaultRenderCacheMB
+	if n, err := strconv.Atoi(os.Getenv("RENDER_CACHE_MB")); err == nil && n > 0 {
+		cacheMB = n
+	}
+	renderCache, err = diskcache.New(cacheDir, int64(cacheMB)<<20)
+	if err != nil {
+		log.Fatalf("cannot open render cache: %v", err)
+	}
+
+	maxActive := int32(defaultMaxActiveRenders)
+	if n, err := strconv.Atoi(os.Getenv("MAX_CONCURRENT_RENDERS")); err == nil && n > 0 {
+		maxActive = int32(n)
+	}
+	maxQueued := int32(defaultMaxQueuedRenders)
+	if n, err := strconv.Atoi(os.Getenv("MAX_QUEUED_RENDERS")); err == nil && n >= 0 {
+		maxQueued = int32(n)
+	}
+	renderLimits = newRenderLimiter(maxActive, maxQueued)
 
-	srv := grpc.NewServer()
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(renderLimits.unaryInterceptor),
+		grpc.StreamInterceptor(renderLimits.streamInterceptor))
 	pb.RegisterRenderServer(srv, server{})
 	srv.Serve(l)
 }
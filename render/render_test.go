@@ -0,0 +1,134 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/gifinator/internal/blobstore"
+	"github.com/GoogleCloudPlatform/gifinator/internal/diskcache"
+	"github.com/GoogleCloudPlatform/gifinator/internal/gcsref"
+	pb "github.com/GoogleCloudPlatform/gifinator/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func TestCheckRenderSettings(t *testing.T) {
+	region := func(x, y, w, h int32) *pb.Region {
+		return &pb.Region{X: x, Y: y, Width: w, Height: h}
+	}
+	tests := []struct {
+		desc string
+		req  pb.RenderRequest
+		want codes.Code
+	}{
+		{"defaults", pb.RenderRequest{}, codes.OK},
+		{"largest frame", pb.RenderRequest{Width: 1024, Height: 1024}, codes.OK},
+		{"frame too wide", pb.RenderRequest{Width: 1025, Height: 100}, codes.InvalidArgument},
+		{"frame too tall", pb.RenderRequest{Width: 100, Height: 1025}, codes.InvalidArgument},
+		{"negative size", pb.RenderRequest{Width: -1, Height: 100}, codes.InvalidArgument},
+		{"most samples", pb.RenderRequest{SamplesPerPixel: 64}, codes.OK},
+		{"too many samples", pb.RenderRequest{SamplesPerPixel: 65}, codes.InvalidArgument},
+		{"negative samples", pb.RenderRequest{SamplesPerPixel: -1}, codes.InvalidArgument},
+		{"most iterations", pb.RenderRequest{Iterations: 4}, codes.OK},
+		{"too many iterations", pb.RenderRequest{Iterations: 5}, codes.InvalidArgument},
+		{"tile of a poster", pb.RenderRequest{Width: 2048, Height: 2048, Region: region(1024, 1024, 1024, 1024)}, codes.OK},
+		{"tile too big", pb.RenderRequest{Width: 2048, Height: 2048, Region: region(0, 0, 2048, 1024)}, codes.InvalidArgument},
+		{"tile outside the frame", pb.RenderRequest{Width: 2048, Height: 2048, Region: region(1500, 0, 1000, 1000)}, codes.InvalidArgument},
+		{"empty tile", pb.RenderRequest{Width: 2048, Height: 2048, Region: region(0, 0, 0, 100)}, codes.InvalidArgument},
+		{"tile of a huge frame", pb.RenderRequest{Width: 1 << 30, Height: 1 << 30, Region: region(0, 0, 10, 10)}, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		req := tt.req
+		err := checkRenderSettings(&req)
+		if got := grpc.Code(err); got != tt.want {
+			t.Errorf("%s: checkRenderSettings = %v, want %v", tt.desc, err, tt.want)
+		}
+	}
+}
+
+func TestCheckRenderSettingsDefaults(t *testing.T) {
+	req := pb.RenderRequest{Width: 150}
+	if err := checkRenderSettings(&req); err != nil {
+		t.Fatal(err)
+	}
+	if req.Width != 150 || req.Height != defaultHeight || req.SamplesPerPixel != defaultSamplesPerPixel || req.Iterations != defaultIterations {
+		t.Errorf("checkRenderSettings filled in %dx%d, %d samples, %d iterations", req.Width, req.Height, req.SamplesPerPixel, req.Iterations)
+	}
+}
+
+func TestCacheObjectWorkDirs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "render")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(s blobstore.BlobStore, c *diskcache.Cache) { blobStore, renderCache = s, c }(blobStore, renderCache)
+	blobStore = blobstore.NewLocal(filepath.Join(dir, "store"), "")
+	renderCache, err = diskcache.New(filepath.Join(dir, "cache"), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	mtl := gcsref.MustParseRef("file://bucket/job_1.mtl")
+	texture := gcsref.MustParseRef("file://bucket/textures/k8s.png")
+	blobStore.Put(ctx, texture, strings.NewReader("pixels"), "")
+
+	// Two requests for different versions of the same object each see the
+	// version that was current when they fetched it
+	tests := []struct {
+		workDir  string
+		contents string
+	}{
+		{"a", "first"},
+		{"b", "second version"},
+		{"c", "second version"},
+	}
+	for _, tt := range tests {
+		workDir := filepath.Join(dir, tt.workDir)
+		os.Mkdir(workDir, 0755)
+		blobStore.Put(ctx, mtl, strings.NewReader(tt.contents), "")
+		for _, ref := range []gcsref.Ref{mtl, texture} {
+			if _, err := cacheObject(ctx, workDir, ref); err != nil {
+				t.Fatalf("cacheObject(%s, %v) = %v", tt.workDir, ref, err)
+			}
+		}
+	}
+	for _, tt := range tests {
+		if b, err := ioutil.ReadFile(filepath.Join(dir, tt.workDir, "job_1.mtl")); err != nil || string(b) != tt.contents {
+			t.Errorf("%s/job_1.mtl holds %q, %v, want %q", tt.workDir, b, err, tt.contents)
+		}
+		if b, err := ioutil.ReadFile(filepath.Join(dir, tt.workDir, "k8s.png")); err != nil || string(b) != "pixels" {
+			t.Errorf("%s/k8s.png holds %q, %v", tt.workDir, b, err)
+		}
+	}
+
+	// Cleaning up a working directory leaves the cache alone
+	os.RemoveAll(filepath.Join(dir, "a"))
+	os.Mkdir(filepath.Join(dir, "d"), 0755)
+	if _, err := cacheObject(ctx, filepath.Join(dir, "d"), texture); err != nil {
+		t.Errorf("cacheObject after cleaning up = %v", err)
+	}
+	if _, err := cacheObject(ctx, filepath.Join(dir, "d"), gcsref.MustParseRef("file://bucket/missing.png")); err == nil {
+		t.Errorf("cacheObject of a missing object succeeded")
+	}
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"image"
+	"math/rand"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/gifinator/internal/scene"
+	pb "github.com/GoogleCloudPlatform/gifinator/proto"
+	"github.com/fogleman/pt/pt"
+	"golang.org/x/net/context"
+)
+
+/**
+ * Every image is traced here rather than with pt's Renderer. A call to
+ * Renderer.Render traces the whole image and cannot be stopped part way, and
+ * most presets render in a single iteration, so a cancelled render would run
+ * to the end anyway. The tracer takes the same samples the renderer would,
+ * one per pixel per pass, but checks for cancellation before every row.
+ * It keeps the running sum of each pixel's samples, so that a render can be
+ * traced in several passes and saved after any of them.
+ */
+
+// tracer traces a region of an image, one pass at a time.
+type tracer struct {
+	scene   *pt.Scene
+	camera  *pt.Camera
+	sampler pt.Sampler
+
+	// width and height are the size of the whole image, and region the part
+	// of it that is traced
+	width  int
+	height int
+	region image.Rectangle
+
+	sums   []pt.Color
+	passes int
+}
+
+func newTracer(renderScene *pt.Scene, camera *pt.Camera, sampler pt.Sampler, width int, height int, region image.Rectangle) *tracer {
+	return &tracer{
+		scene:   renderScene,
+		camera:  camera,
+		sampler: sampler,
+		width:   width,
+		height:  height,
+		region:  region,
+		sums:    make([]pt.Color, region.Dx()*region.Dy()),
+	}
+}
+
+// buildTracer sets up a tracer for the object turned by rotation in desc,
+// covering region, or the whole image if region is nil.
+func buildTracer(desc *scene.Description, mesh *pt.Mesh, rotation float64, width int32, height int32, samplesPerPixel int32, region *pb.Region) (*tracer, error) {
+	renderScene, camera, err := desc.BuildWithMesh(mesh, rotation)
+	if err != nil {
+		return nil, err
+	}
+	renderScene.Compile()
+	sampler := pt.NewSampler(int(samplesPerPixel), 16)
+	bounds := image.Rect(0, 0, int(width), int(height))
+	if region != nil {
+		bounds = image.Rect(int(region.X), int(region.Y), int(region.X+region.Width), int(region.Y+region.Height))
+	}
+	return newTracer(renderScene, camera, sampler, int(width), int(height), bounds), nil
+}
+
+// trace adds passes more samples to every pixel. It gives up between rows
+// once ctx is done, leaving the image part way through a pass.
+func (t *tracer) trace(ctx context.Context, passes int) error {
+	w, h := t.region.Dx(), t.region.Dy()
+	ncpu := runtime.NumCPU()
+	var wg sync.WaitGroup
+	for i := 0; i < ncpu; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(time.Now().UnixNano() + int64(i)))
+			for y := i; y < h && ctx.Err() == nil; y += ncpu {
+				for x := 0; x < w; x++ {
+					c := t.sums[y*w+x]
+					for n := 0; n < passes; n++ {
+						ray := t.camera.CastRay(t.region.Min.X+x, t.region.Min.Y+y, t.width, t.height, rnd.Float64(), rnd.Float64(), rnd)
+						c = c.Add(t.sampler.Sample(t.scene, ray, rnd))
+					}
+					t.sums[y*w+x] = c
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	t.passes += passes
+	return nil
+}
+
+// image returns the region as traced so far.
+func (t *tracer) image() *image.RGBA64 {
+	w, h := t.region.Dx(), t.region.Dy()
+	img := image.NewRGBA64(image.Rect(0, 0, w, h))
+	if t.passes == 0 {
+		return img
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			// Gamma correct as the renderer does when saving an image
+			c := t.sums[y*w+x].DivScalar(float64(t.passes)).Pow(1 / 2.2)
+			img.SetRGBA64(x, y, c.RGBA64())
+		}
+	}
+	return img
+}
@@ -0,0 +1,98 @@
+/*
+ * Copyright 2017 Google Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"image"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/fogleman/pt/pt"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// slowSampler stands in for a scene that takes a long time to trace.
+type slowSampler struct{}
+
+func (slowSampler) Sample(scene *pt.Scene, ray pt.Ray, rnd *rand.Rand) pt.Color {
+	time.Sleep(100 * time.Microsecond)
+	return pt.White
+}
+
+func newSlowTracer(width, height int) *tracer {
+	camera := pt.LookAt(pt.V(0, 0, -5), pt.V(0, 0, 0), pt.V(0, 1, 0), 45)
+	return newTracer(nil, &camera, slowSampler{}, width, height, image.Rect(0, 0, width, height))
+}
+
+func TestTraceCancel(t *testing.T) {
+	tests := []struct {
+		desc        string
+		cancelAfter time.Duration
+		timeout     time.Duration
+		want        codes.Code
+	}{
+		// A single pass over the whole image takes minutes, but each row only
+		// a few milliseconds
+		{"cancelled before it starts", 0, 0, codes.Canceled},
+		{"cancelled part way", 50 * time.Millisecond, 0, codes.Canceled},
+		{"past its deadline", 0, 50 * time.Millisecond, codes.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		ctx, cancel := context.WithCancel(context.Background())
+		if tt.timeout > 0 {
+			ctx, cancel = context.WithTimeout(context.Background(), tt.timeout)
+		} else if tt.cancelAfter == 0 {
+			cancel()
+		} else {
+			time.AfterFunc(tt.cancelAfter, cancel)
+		}
+
+		tr := newSlowTracer(16, 20000)
+		start := time.Now()
+		err := renderError(ctx, tr.trace(ctx, 1))
+		elapsed := time.Since(start)
+		cancel()
+
+		if got := grpc.Code(err); got != tt.want {
+			t.Errorf("%s: trace = %v, want %v", tt.desc, err, tt.want)
+		}
+		if elapsed > tt.cancelAfter+tt.timeout+500*time.Millisecond {
+			t.Errorf("%s: trace took %v to stop", tt.desc, elapsed)
+		}
+		if tr.passes != 0 {
+			t.Errorf("%s: trace counted %d passes, want 0", tt.desc, tr.passes)
+		}
+	}
+}
+
+func TestTracePasses(t *testing.T) {
+	tr := newSlowTracer(8, 8)
+	for i := 1; i <= 3; i++ {
+		if err := tr.trace(context.Background(), 1); err != nil {
+			t.Fatal(err)
+		}
+		if tr.passes != i {
+			t.Errorf("after pass %d, passes = %d", i, tr.passes)
+		}
+	}
+	if got := tr.image().Bounds(); got != image.Rect(0, 0, 8, 8) {
+		t.Errorf("image is %v, want 8x8", got)
+	}
+}